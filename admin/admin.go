@@ -0,0 +1,88 @@
+// Package admin runs the debugging surface of a server on separate admin
+// ports, so that it can be kept off the public port and turned off entirely
+// in production: server reflection and channelz on an admin gRPC port, and
+// pprof, configuration and runtime toggles on an admin HTTP port.
+package admin
+
+import (
+	"flag"
+	"google.golang.org/grpc"
+	channelz "google.golang.org/grpc/channelz/service"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"grpc-go-course/logging"
+	"log"
+	"net"
+)
+
+// Flags configure the admin ports. The gRPC port is off unless Addr is set
+// and at least one of Reflection and Channelz is turned on; the HTTP port is
+// off unless HTTPAddr is set.
+type Flags struct {
+	Addr       string
+	Reflection bool
+	Channelz   bool
+	HTTPAddr   string
+}
+
+// Register defines the admin flags on fs. Everything is off by default.
+func (f *Flags) Register(fs *flag.FlagSet) {
+	fs.StringVar(&f.Addr, "admin-grpc-addr", "", "address of the admin gRPC port serving reflection and channelz, empty to disable")
+	fs.BoolVar(&f.Reflection, "reflection", false, "serve gRPC server reflection on the admin port")
+	fs.BoolVar(&f.Channelz, "channelz", false, "serve channelz on the admin port")
+	fs.StringVar(&f.HTTPAddr, "admin-http-addr", "", "address of the admin HTTP server (pprof, config, version, log level, draining), localhost if it has no host, empty to disable")
+}
+
+// Enabled reports whether the admin gRPC port is turned on.
+func (f *Flags) Enabled() bool {
+	return f.Addr != "" && (f.Reflection || f.Channelz)
+}
+
+// Serve starts the admin gRPC server in the background, or returns nil if it
+// is disabled. Besides reflection and channelz it only serves healthSrv: the
+// application services of public are not registered there, but reflection
+// describes them, so that grpcurl can list them and, with -protoset-out, save
+// the descriptors that calls on the public port, which has no reflection,
+// need to be given with -protoset. opts typically carry the same credentials
+// as the public port.
+func (f *Flags) Serve(public *grpc.Server, healthSrv healthpb.HealthServer, opts ...grpc.ServerOption) (*grpc.Server, error) {
+	if !f.Enabled() {
+		return nil, nil
+	}
+	listen, err := net.Listen("tcp", f.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := grpc.NewServer(opts...)
+	healthpb.RegisterHealthServer(s, healthSrv)
+	if f.Reflection {
+		reflection.Register(describer{Server: s, public: public})
+	}
+	if f.Channelz {
+		channelz.RegisterChannelzServiceToServer(s)
+	}
+
+	go func() {
+		if err := s.Serve(listen); err != nil {
+			log.Fatalf("Failed to serve admin port %v", err)
+		}
+	}()
+	logging.Infof("Admin port started at %s (reflection: %v, channelz: %v)", f.Addr, f.Reflection, f.Channelz)
+	return s, nil
+}
+
+// describer is the admin server as seen by reflection: it also describes the
+// services of the public server, which it does not serve.
+type describer struct {
+	*grpc.Server
+	public *grpc.Server
+}
+
+func (d describer) GetServiceInfo() map[string]grpc.ServiceInfo {
+	info := d.public.GetServiceInfo()
+	for name, si := range d.Server.GetServiceInfo() {
+		info[name] = si
+	}
+	return info
+}
@@ -0,0 +1,67 @@
+package admin
+
+import (
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"sync"
+)
+
+// Drainer owns the serving status a server reports through its health
+// service. Marking the server as draining reports NOT_SERVING, so that
+// health checking clients move their traffic to other replicas, while the
+// server keeps handling the calls it still receives.
+type Drainer struct {
+	health   *health.Server
+	services []string
+
+	mu       sync.Mutex
+	draining bool
+	serving  bool
+}
+
+// NewDrainer returns a Drainer reporting the status of the server as a whole
+// and of the given services on h. The server starts out serving.
+func NewDrainer(h *health.Server, services ...string) *Drainer {
+	d := &Drainer{
+		health:   h,
+		services: append([]string{""}, services...),
+		serving:  true,
+	}
+	d.update()
+	return d
+}
+
+// SetServing records whether the server is able to serve, for instance
+// whether its database is reachable. It is reported unless draining.
+func (d *Drainer) SetServing(serving bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.serving = serving
+	d.update()
+}
+
+// SetDraining marks the server as draining, or back in service.
+func (d *Drainer) SetDraining(draining bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.draining = draining
+	d.update()
+}
+
+// Draining reports whether the server is marked as draining.
+func (d *Drainer) Draining() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draining
+}
+
+// update must be called with d.mu held.
+func (d *Drainer) update() {
+	st := healthpb.HealthCheckResponse_SERVING
+	if d.draining || !d.serving {
+		st = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	for _, svc := range d.services {
+		d.health.SetServingStatus(svc, st)
+	}
+}
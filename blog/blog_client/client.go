@@ -1,101 +1,130 @@
+// Command blog_client is a command line tool for the BlogService.
+//
+// Usage:
+//
+//	blog_client [global flags] <command> [command flags] [args]
+//
+// Commands:
+//
+//	create  -author A -title T -content C   create a blog
+//	get     <id>                            print a blog
+//	update  <id> [-author A] [-title T] [-content C]
+//	                                        change the given fields of a blog
+//	delete  <id>                            delete a blog
+//	list                                    print all blogs
+//	import  [-f file]                       create blogs from NDJSON (default stdin)
+//	export  [-f file]                       write all blogs as NDJSON (default stdout)
+//
+// Run "blog_client -h" for the global flags.
 package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
-	"grpc-go-course/blog/blogpb"
-	"io"
+	"grpc-go-course/blog/client"
+	"grpc-go-course/dial"
 	"log"
+	"os"
+	"time"
 )
 
+// command is a blog_client subcommand. run receives the arguments following
+// the command name.
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, c *client.Client, out printer, args []string) error
+}
+
+var commands = []command{
+	{"create", "create -author A -title T -content C", runCreate},
+	{"get", "get <id>", runGet},
+	{"update", "update <id> [-author A] [-title T] [-content C]", runUpdate},
+	{"delete", "delete <id>", runDelete},
+	{"list", "list", runList},
+	{"import", "import [-f file]", runImport},
+	{"export", "export [-f file]", runExport},
+}
+
 func main() {
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	certFile := "ssl/ca.crt"
-	tls := true
-	opts := grpc.WithInsecure()
-	if tls {
-		creds, err := credentials.NewClientTLSFromFile(certFile, "")
-		if err != nil {
-			log.Fatalf("Could not construct credentials %v", err)
-		}
-		opts = grpc.WithTransportCredentials(creds)
-	}
+	log.SetFlags(0)
+	log.SetPrefix("blog_client: ")
+
+	var df dial.Flags
+	df.Register(flag.CommandLine, dial.Defaults{
+		Addr:    "localhost:50051",
+		CAFile:  "ssl/ca.crt",
+		Timeout: 10 * time.Second,
+	})
+	token := flag.String("token", os.Getenv("BLOG_TOKEN"), "bearer token sent with every call (default $BLOG_TOKEN)")
+	output := flag.String("o", "table", "output format: table or json")
+	flag.Usage = usage
+	flag.Parse()
 
-	cc, err := grpc.Dial("localhost:50051", opts)
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := findCommand(flag.Arg(0))
+	if !ok {
+		log.Printf("unknown command %q", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+	out, err := newPrinter(*output, os.Stdout)
 	if err != nil {
-		log.Fatalf("Could not connect: %v", err)
+		log.Fatal(err)
 	}
-	defer cc.Close()
 
-	c := blogpb.NewBlogServiceClient(cc)
-	listBlogs(c)
-	//createBlog(c)
-	//readBlog(c)
-	//updateBlog(c)
-	//deleteBlog(c)
-}
-
-func listBlogs(c blogpb.BlogServiceClient) {
-	stream, err := c.ListBlogs(context.Background(), &blogpb.ListBlogsRequest{})
+	target, err := df.Target()
 	if err != nil {
-		log.Fatalf("Failed to list blogs %v\n", err)
+		log.Fatal(err)
 	}
-
-	for {
-		res, err := stream.Recv()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			log.Fatalf("Failed to receive stream from server %v\n", err)
-		}
-		fmt.Println("Blog received: ", res.GetBlog())
+	chanOpts, err := df.ChannelOptions()
+	if err != nil {
+		log.Fatal(err)
 	}
-}
-
-func deleteBlog(c blogpb.BlogServiceClient) {
-	res, err := c.DeleteBlog(context.Background(), &blogpb.DeleteBlogRequest{BlogId: "5f500998f9dee1a1841685fb"})
+	// Retries are driven by the service config, so the client's own retry
+	// loop is turned off to avoid multiplying attempts.
+	opts := []client.Option{
+		client.WithTimeout(df.Timeout),
+		client.WithRetry(client.NoRetry),
+		client.WithDialOptions(chanOpts...),
+	}
+	if !df.Plaintext {
+		opts = append(opts, client.WithTLS(df.CAFile, df.ServerName))
+	}
+	if *token != "" {
+		opts = append(opts, client.WithToken(*token))
+	}
+	c, err := client.Dial(context.Background(), target, opts...)
 	if err != nil {
-		log.Fatalf("Failed to delete blog %v\n", err)
+		log.Fatalf("Could not connect: %v", err)
 	}
-	fmt.Printf("Blog deleted %v", res)
-}
+	defer c.Close()
 
-func updateBlog(c blogpb.BlogServiceClient) {
-	fmt.Println("Getting blog")
-	readRes, err := c.UpdateBlog(context.Background(), &blogpb.UpdateBlogRequest{Blog: &blogpb.Blog{
-		Id:       "5f500998f9dee1a1841685fb",
-		AuthorId: "Cool Again",
-		Title:    "Again",
-		Content:  "Hello Cool Guy",
-	}})
-	if err != nil {
-		log.Fatalf("Unexpected error: %v", err)
+	if err := cmd.run(context.Background(), c, out, flag.Args()[1:]); err != nil {
+		c.Close()
+		log.Fatalf("%s: %v", cmd.name, err)
 	}
-	fmt.Printf("Blog received %v\n", readRes)
 }
 
-func readBlog(c blogpb.BlogServiceClient) {
-	fmt.Println("Getting blog")
-	readRes, err := c.ReadBlog(context.Background(), &blogpb.ReadBlogRequest{BlogId: "5f50030183dcd90c273af889"})
-	if err != nil {
-		log.Fatalf("Unexpected error: %v", err)
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
 	}
-	fmt.Printf("Blog received %v\n", readRes)
+	return command{}, false
 }
 
-func createBlog(c blogpb.BlogServiceClient) {
-	fmt.Println("Creating blog")
-	blog := &blogpb.Blog{
-		AuthorId: "Jane",
-		Title:    "My 2nd Blog",
-		Content:  "Content of 2nd blog",
-	}
-	createRes, err := c.CreateBlog(context.Background(), &blogpb.CreateBlogRequest{Blog: blog})
-	if err != nil {
-		log.Fatalf("Unexpected error: %v", err)
+func usage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintf(w, "Usage: blog_client [global flags] <command> [command flags] [args]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %s\n", cmd.usage)
 	}
-	fmt.Printf("Blog has been created %v\n", createRes)
+	fmt.Fprintf(w, "\nGlobal flags:\n")
+	flag.PrintDefaults()
 }
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"google.golang.org/protobuf/encoding/protojson"
+	"grpc-go-course/blog/blogpb"
+	"grpc-go-course/blog/client"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+func runCreate(ctx context.Context, c *client.Client, out printer, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	author := fs.String("author", "", "author ID")
+	title := fs.String("title", "", "title")
+	content := fs.String("content", "", `content, or "-" to read it from stdin`)
+	fs.Parse(args)
+
+	text, err := readContent(*content)
+	if err != nil {
+		return err
+	}
+	blog, err := c.Create(ctx, &blogpb.Blog{AuthorId: *author, Title: *title, Content: text})
+	if err != nil {
+		return err
+	}
+	return out.blog(blog)
+}
+
+func runGet(ctx context.Context, c *client.Client, out printer, args []string) error {
+	fs := flag.NewFlagSet("get", flag.ExitOnError)
+	fs.Parse(args)
+	id, err := singleID(fs)
+	if err != nil {
+		return err
+	}
+
+	blog, err := c.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return out.blog(blog)
+}
+
+func runUpdate(ctx context.Context, c *client.Client, out printer, args []string) error {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	author := fs.String("author", "", "new author ID")
+	title := fs.String("title", "", "new title")
+	content := fs.String("content", "", `new content, or "-" to read it from stdin`)
+	id, rest := splitID(args)
+	fs.Parse(rest)
+	if id == "" {
+		id = fs.Arg(0)
+	}
+	if id == "" {
+		return errors.New("missing blog ID")
+	}
+
+	// Only the fields given on the command line are sent, in the update
+	// mask, so that concurrent changes to the others are kept.
+	blog := &blogpb.Blog{Id: id}
+	var paths []string
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "author":
+			blog.AuthorId = *author
+			paths = append(paths, "author_id")
+		case "title":
+			blog.Title = *title
+			paths = append(paths, "title")
+		case "content":
+			blog.Content, err = readContent(*content)
+			paths = append(paths, "content")
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errors.New("nothing to update, give -author, -title or -content")
+	}
+
+	blog, err = c.Patch(ctx, blog, paths...)
+	if err != nil {
+		return err
+	}
+	return out.blog(blog)
+}
+
+func runDelete(ctx context.Context, c *client.Client, out printer, args []string) error {
+	fs := flag.NewFlagSet("delete", flag.ExitOnError)
+	fs.Parse(args)
+	id, err := singleID(fs)
+	if err != nil {
+		return err
+	}
+
+	blog, err := c.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	return out.blog(blog)
+}
+
+func runList(ctx context.Context, c *client.Client, out printer, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	fs.Parse(args)
+
+	blogs, err := c.List(ctx).All()
+	if err != nil {
+		return err
+	}
+	return out.blogs(blogs)
+}
+
+// runImport creates one blog per line of NDJSON input. IDs in the input are
+// ignored since the server assigns them.
+func runImport(ctx context.Context, c *client.Client, out printer, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	file := fs.String("f", "-", `NDJSON file to read, "-" for stdin`)
+	fs.Parse(args)
+
+	r := io.Reader(os.Stdin)
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var created []*blogpb.Blog
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		blog := &blogpb.Blog{}
+		if err := protojson.Unmarshal([]byte(text), blog); err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		blog.Id = ""
+		res, err := c.Create(ctx, blog)
+		if err != nil {
+			// The blogs of the previous lines are there to stay: print
+			// them, so that the import can be resumed after line.
+			if len(created) > 0 {
+				out.blogs(created)
+			}
+			return fmt.Errorf("line %d: %v (the %d blogs of the previous lines were created)", line, err, len(created))
+		}
+		created = append(created, res)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return out.blogs(created)
+}
+
+// runExport writes every blog as one line of JSON, in the format read by
+// import.
+func runExport(ctx context.Context, c *client.Client, out printer, args []string) (err error) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	file := fs.String("f", "-", `NDJSON file to write, "-" for stdout`)
+	fs.Parse(args)
+
+	w := io.Writer(os.Stdout)
+	if *file != "-" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		// A failed close may mean the data never reached the disk.
+		defer func() {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+
+	it := c.List(ctx)
+	defer it.Close()
+	for it.Next() {
+		data, err := protojson.Marshal(it.Blog())
+		if err != nil {
+			return err
+		}
+		if _, err := bw.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func singleID(fs *flag.FlagSet) (string, error) {
+	if fs.NArg() != 1 {
+		return "", errors.New("expected exactly one blog ID")
+	}
+	return fs.Arg(0), nil
+}
+
+// splitID allows the blog ID to come before the flags, as in
+// "update <id> -title T", which the flag package would otherwise stop at.
+func splitID(args []string) (string, []string) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return args[0], args[1:]
+	}
+	return "", args
+}
+
+func readContent(content string) (string, error) {
+	if content != "-" {
+		return content, nil
+	}
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
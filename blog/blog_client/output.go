@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"google.golang.org/protobuf/encoding/protojson"
+	"grpc-go-course/blog/blogpb"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// maxContentWidth is how much of a blog's content the table output shows.
+const maxContentWidth = 40
+
+// printer writes blogs in the output format picked with -o.
+type printer interface {
+	blog(b *blogpb.Blog) error
+	blogs(bs []*blogpb.Blog) error
+}
+
+func newPrinter(format string, w io.Writer) (printer, error) {
+	switch format {
+	case "table":
+		return tablePrinter{w}, nil
+	case "json":
+		return jsonPrinter{w}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
+
+type tablePrinter struct {
+	w io.Writer
+}
+
+func (p tablePrinter) blog(b *blogpb.Blog) error {
+	return p.blogs([]*blogpb.Blog{b})
+}
+
+func (p tablePrinter) blogs(bs []*blogpb.Blog) error {
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tAUTHOR\tTITLE\tCONTENT")
+	for _, b := range bs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", b.GetId(), b.GetAuthorId(), b.GetTitle(), summarize(b.GetContent()))
+	}
+	return tw.Flush()
+}
+
+// summarize shortens content to a single table cell.
+func summarize(content string) string {
+	content = strings.Join(strings.Fields(content), " ")
+	if r := []rune(content); len(r) > maxContentWidth {
+		return string(r[:maxContentWidth-3]) + "..."
+	}
+	return content
+}
+
+type jsonPrinter struct {
+	w io.Writer
+}
+
+var jsonOptions = protojson.MarshalOptions{Multiline: true, Indent: "  ", EmitUnpopulated: true}
+
+func (p jsonPrinter) blog(b *blogpb.Blog) error {
+	data, err := jsonOptions.Marshal(b)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(p.w, "%s\n", data)
+	return err
+}
+
+// blogs prints a JSON array so the output can be fed to tools like jq.
+func (p jsonPrinter) blogs(bs []*blogpb.Blog) error {
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i, b := range bs {
+		data, err := jsonOptions.Marshal(b)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString("\n  ")
+		sb.WriteString(strings.Replace(string(data), "\n", "\n  ", -1))
+	}
+	if len(bs) > 0 {
+		sb.WriteString("\n")
+	}
+	sb.WriteString("]\n")
+	_, err := io.WriteString(p.w, sb.String())
+	return err
+}
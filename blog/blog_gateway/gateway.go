@@ -0,0 +1,68 @@
+// Command blog_gateway serves the BlogService as a REST/JSON API, proxying
+// every request to a blog server over gRPC:
+//
+//	POST   /v1/blogs       CreateBlog, the body is the blog
+//	GET    /v1/blogs/{id}  ReadBlog
+//	PATCH  /v1/blogs/{id}  UpdateBlog, only the fields in the body are changed
+//	DELETE /v1/blogs/{id}  DeleteBlog
+//	GET    /v1/blogs       ListBlogs
+//	GET    /openapi.json   the OpenAPI document of the API
+//
+// ListBlogs returns a JSON array, or a stream of newline delimited JSON blogs
+// when called with "Accept: application/x-ndjson" or "?format=ndjson".
+// gRPC status codes are translated to the matching HTTP status codes.
+package main
+
+import (
+	"context"
+	"flag"
+	"grpc-go-course/blog/blogpb"
+	"grpc-go-course/dial"
+	"grpc-go-course/gateway"
+	"log"
+	"net/http"
+)
+
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	address := flag.String("http-addr", "0.0.0.0:8080", "address to serve HTTP on")
+	specFile := flag.String("openapi", "", "OpenAPI document to serve at "+gateway.SpecPath+" (default: the one built into the binary)")
+	var tlsFlags gateway.TLSFlags
+	tlsFlags.Register(flag.CommandLine)
+	var df dial.Flags
+	df.Register(flag.CommandLine, dial.Defaults{
+		Addr:   "localhost:50051",
+		CAFile: "ssl/ca.crt",
+	})
+	flag.Parse()
+	tlsCfg, err := tlsFlags.Config()
+	if err != nil {
+		log.Fatalf("Failed to load the HTTPS certificate: %v", err)
+	}
+
+	cc, err := df.Dial(context.Background())
+	if err != nil {
+		log.Fatalf("Could not connect to the blog server: %v", err)
+	}
+	defer cc.Close()
+
+	mux := gateway.NewMux()
+	if err := blogpb.RegisterBlogServiceHandler(context.Background(), mux, cc); err != nil {
+		log.Fatalf("Failed to register the BlogService handler: %v", err)
+	}
+	lister := &listHandler{mux: mux, client: blogpb.NewBlogServiceClient(cc)}
+
+	spec, err := gateway.LoadSpec(*specFile, blogpb.OpenAPI)
+	if err != nil {
+		log.Fatalf("Failed to load the OpenAPI document: %v", err)
+	}
+	handler := gateway.WithSpec(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == "/v1/blogs" {
+			lister.ServeHTTP(w, r)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}), spec)
+
+	gateway.ListenAndServe(*address, handler, tlsCfg)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"grpc-go-course/blog/blogpb"
+	"grpc-go-course/gateway"
+	"io"
+	"mime"
+	"net/http"
+)
+
+const ndjsonContentType = "application/x-ndjson"
+
+// listHandler serves GET /v1/blogs. It replaces the generated handler, which
+// wraps every streamed message in a {"result": ...} object, with a plain JSON
+// array or NDJSON stream of blogs.
+type listHandler struct {
+	mux    *runtime.ServeMux
+	client blogpb.BlogServiceClient
+}
+
+func (h *listHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, err := runtime.AnnotateContext(r.Context(), h.mux, r)
+	if err != nil {
+		runtime.HTTPError(ctx, h.mux, gateway.Marshaler, w, r, err)
+		return
+	}
+	stream, err := h.client.ListBlogs(ctx, &blogpb.ListBlogsRequest{})
+	if err != nil {
+		runtime.HTTPError(ctx, h.mux, gateway.Marshaler, w, r, err)
+		return
+	}
+
+	ndjson := wantsNDJSON(r)
+	flusher, _ := w.(http.Flusher)
+	n := 0
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if n == 0 {
+				runtime.HTTPError(ctx, h.mux, gateway.Marshaler, w, r, err)
+				return
+			}
+			// The status line is gone already; cut the response short so
+			// that the client sees invalid JSON rather than a partial list.
+			panic(http.ErrAbortHandler)
+		}
+		data, err := gateway.Marshaler.Marshal(res.GetBlog())
+		if err != nil {
+			runtime.HTTPError(ctx, h.mux, gateway.Marshaler, w, r, err)
+			return
+		}
+
+		if n == 0 {
+			if ndjson {
+				w.Header().Set("Content-Type", ndjsonContentType)
+			} else {
+				w.Header().Set("Content-Type", gateway.Marshaler.ContentType())
+				w.Write([]byte("["))
+			}
+		} else if !ndjson {
+			w.Write([]byte(","))
+		}
+		w.Write(data)
+		if ndjson {
+			w.Write([]byte("\n"))
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		n++
+	}
+
+	switch {
+	case ndjson && n == 0:
+		w.Header().Set("Content-Type", ndjsonContentType)
+	case !ndjson && n == 0:
+		w.Header().Set("Content-Type", gateway.Marshaler.ContentType())
+		w.Write([]byte("[]"))
+	case !ndjson:
+		w.Write([]byte("]"))
+	}
+}
+
+func wantsNDJSON(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "ndjson" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Accept"))
+	return err == nil && mediaType == ndjsonContentType
+}
@@ -2,33 +2,54 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/status"
+	"grpc-go-course/admin"
 	"grpc-go-course/blog/blogpb"
 	"grpc-go-course/blog/models"
 	"grpc-go-course/db"
+	"grpc-go-course/faults"
+	"grpc-go-course/logging"
+	"grpc-go-course/web"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"time"
 )
 
 var collection *mongo.Collection
 
+const (
+	mongoCheckInterval = 5 * time.Second
+	mongoCheckTimeout  = 2 * time.Second
+)
+
 type server struct{}
 
 func (s *server) ListBlogs(req *blogpb.ListBlogsRequest, stream blogpb.BlogService_ListBlogsServer) error {
-	blogs, err := models.ListAll(collection)
+	ctx := stream.Context()
+	blogs, err := models.ListAll(ctx, collection)
 	if err != nil {
+		if ctx.Err() != nil {
+			return contextError(ctx)
+		}
 		return status.Errorf(codes.Internal, "Failed to list blogs %v", err)
 	}
 
 	for _, b := range blogs {
+		if ctx.Err() != nil {
+			return contextError(ctx)
+		}
 		if err := stream.Send(&blogpb.ListBlogsResponse{Blog: mapDataToBlogpb(b)}); err != nil {
 			return err
 		}
@@ -48,11 +69,17 @@ func (s *server) DeleteBlog(ctx context.Context, req *blogpb.DeleteBlogRequest)
 		ID: oid,
 	}
 
-	res, err := item.Delete(collection)
+	res, err := item.Delete(ctx, collection)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, contextError(ctx)
+		}
 		return nil, status.Error(codes.Internal, fmt.Sprintf("Failed to delete blog %v", err))
 	}
-	fmt.Printf("Deleted %v blog(s)", res.DeletedCount)
+	if res.DeletedCount == 0 {
+		return nil, status.Errorf(codes.NotFound, "Cannot find blog with specified ID %v", id)
+	}
+	logging.Debugf("Deleted %v blog(s)", res.DeletedCount)
 	return &blogpb.DeleteBlogResponse{Blog: mapDataToBlogpb(item)}, nil
 }
 
@@ -71,9 +98,26 @@ func (s *server) UpdateBlog(ctx context.Context, req *blogpb.UpdateBlogRequest)
 		Title:    blog.GetTitle(),
 	}
 
-	data, err := item.Update(collection)
+	// Only a request without a mask replaces the whole blog. The gateway
+	// always sets one, built from the body of the PATCH.
+	var data *models.BlogItem
+	if mask := req.GetUpdateMask(); mask != nil {
+		if len(mask.GetPaths()) == 0 {
+			return nil, status.Error(codes.InvalidArgument, "Update mask has no fields to update")
+		}
+		fields, maskErr := maskToFields(mask.GetPaths())
+		if maskErr != nil {
+			return nil, maskErr
+		}
+		data, err = item.Patch(ctx, collection, fields)
+	} else {
+		data, err = item.Update(ctx, collection)
+	}
 
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, contextError(ctx)
+		}
 		if err == mongo.ErrNoDocuments {
 			return nil, status.Error(codes.NotFound, fmt.Sprintf("Cannot find blog with specified ID %v", err))
 		}
@@ -91,9 +135,15 @@ func (s *server) ReadBlog(ctx context.Context, req *blogpb.ReadBlogRequest) (*bl
 	data := models.BlogItem{
 		ID: oid,
 	}
-	_, err = data.ById(collection)
+	_, err = data.ById(ctx, collection)
 	if err != nil {
-		return nil, status.Error(codes.NotFound, fmt.Sprintf("Cannot find blog with specified ID %v", err))
+		if ctx.Err() != nil {
+			return nil, contextError(ctx)
+		}
+		if err == mongo.ErrNoDocuments {
+			return nil, status.Error(codes.NotFound, fmt.Sprintf("Cannot find blog with specified ID %v", err))
+		}
+		return nil, status.Error(codes.Internal, fmt.Sprintf("Unexpected Error %v", err))
 	}
 	return &blogpb.ReadBlogResponse{
 		Blog: mapDataToBlogpb(data),
@@ -108,13 +158,16 @@ func (s *server) CreateBlog(ctx context.Context, req *blogpb.CreateBlogRequest)
 		Title:    blog.GetTitle(),
 	}
 
-	res, err := item.Create(collection)
+	res, err := item.Create(ctx, collection)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, fmt.Sprintf("Internal error: %v", err))
+		if ctx.Err() != nil {
+			return nil, contextError(ctx)
+		}
+		return nil, status.Errorf(codes.Internal, "Internal error: %v", err)
 	}
 	oid, ok := res.InsertedID.(primitive.ObjectID)
 	if !ok {
-		return nil, status.Errorf(codes.Internal, fmt.Sprintf("Cannot convert to OID", err))
+		return nil, status.Errorf(codes.Internal, "Cannot convert to OID %v", res.InsertedID)
 	}
 
 	return &blogpb.CreateBlogResponse{Blog: &blogpb.Blog{
@@ -125,6 +178,32 @@ func (s *server) CreateBlog(ctx context.Context, req *blogpb.CreateBlogRequest)
 	}}, nil
 }
 
+// maskToFields maps the paths of an update mask to the document fields they
+// update.
+func maskToFields(paths []string) ([]string, error) {
+	var fields []string
+	for _, p := range paths {
+		switch p {
+		case "author_id", "title", "content":
+			fields = append(fields, p)
+		case "id":
+			// The ID selects the blog, it can't be changed.
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "Cannot update unknown field %q", p)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Update mask selects no field to update")
+	}
+	return fields, nil
+}
+
+// contextError converts the error of a cancelled or expired RPC context into the
+// matching gRPC status (Canceled or DeadlineExceeded).
+func contextError(ctx context.Context) error {
+	return status.FromContextError(ctx.Err()).Err()
+}
+
 func mapDataToBlogpb(data models.BlogItem) *blogpb.Blog {
 	return &blogpb.Blog{
 		Id:       data.ID.Hex(),
@@ -134,40 +213,90 @@ func mapDataToBlogpb(data models.BlogItem) *blogpb.Blog {
 	}
 }
 
+// watchMongo reports the server as NOT_SERVING through the health service
+// while MongoDB can't be reached, so that clients balancing across replicas
+// stop sending it requests.
+func watchMongo(client *mongo.Client, drainer *admin.Drainer) {
+	for range time.Tick(mongoCheckInterval) {
+		ctx, cancel := context.WithTimeout(context.Background(), mongoCheckTimeout)
+		err := client.Ping(ctx, nil)
+		cancel()
+
+		if err != nil {
+			logging.Warnf("MongoDB ping failed: %v", err)
+		}
+		drainer.SetServing(err == nil)
+	}
+}
+
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	address := "0.0.0.0:50051"
+	address := flag.String("addr", "0.0.0.0:50051", "address to listen on")
+	var faultCfg faults.Config
+	faultCfg.RegisterFlags(flag.CommandLine)
+	var webFlags web.Flags
+	webFlags.Register(flag.CommandLine)
+	var adminFlags admin.Flags
+	adminFlags.Register(flag.CommandLine)
+	mongoURI := flag.String("mongo-uri", "mongodb://localhost:27017", "MongoDB connection string")
+	flag.Var(logging.LevelFlag{}, "log-level", "minimum level of the messages logged: debug, info, warn or error")
+	flag.Parse()
 
-	client, err := db.InitClient()
+	client, err := db.InitClient(*mongoURI)
 	if err != nil {
 		log.Fatalf("Failed to connect to mongoDB %v", err)
 	}
 
 	collection = client.Database("mydb").Collection("blog")
 
-	listen, err := net.Listen("tcp", address)
+	listen, err := net.Listen("tcp", *address)
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	opts := []grpc.ServerOption{}
+	opts := faultCfg.ServerOptions()
+	// The admin port gets the same credentials, but no injected faults
+	var adminOpts []grpc.ServerOption
 	tls := true
+	certFile, keyFile := "", ""
 	if tls {
-		certFile := "ssl/server.crt"
-		keyFile := "ssl/server.pem"
+		certFile = "ssl/server.crt"
+		keyFile = "ssl/server.pem"
 		creds, err := credentials.NewServerTLSFromFile(certFile, keyFile)
 		if err != nil {
 			log.Fatalf("Failed loading certs %v", err)
 		}
 
 		opts = append(opts, grpc.Creds(creds))
+		adminOpts = append(adminOpts, grpc.Creds(creds))
 	}
 
 	s := grpc.NewServer(opts...)
+	healthSrv := health.NewServer()
 	blogpb.RegisterBlogServiceServer(s, &server{})
+	healthpb.RegisterHealthServer(s, healthSrv)
+	drainer := admin.NewDrainer(healthSrv, "blog.BlogService")
+	go watchMongo(client, drainer)
+
+	adminSrv, err := adminFlags.Serve(s, healthSrv, adminOpts...)
+	if err != nil {
+		log.Fatalf("Failed to start admin port %v", err)
+	}
+	adminHTTP, err := adminFlags.ServeHTTP(admin.HTTPConfig{Flags: flag.CommandLine, Drainer: drainer})
+	if err != nil {
+		log.Fatalf("Failed to start admin HTTP server %v", err)
+	}
+
+	var webSrv *http.Server
+	if webFlags.Enabled() {
+		if webSrv, err = webFlags.Serve(s, certFile, keyFile); err != nil {
+			log.Fatalf("Failed to serve gRPC-Web: %v", err)
+		}
+		logging.Infof("Serving gRPC-Web at %s", webFlags.Addr)
+	}
 
 	go func() {
-		fmt.Println("Starting Server at " + address)
+		logging.Infof("Starting Server at %s", *address)
 		if err := s.Serve(listen); err != nil {
 			log.Fatalf("Failed to serve %v", err)
 		}
@@ -179,11 +308,21 @@ func main() {
 
 	//Block until a signal is received
 	<-ch
-	fmt.Println("Stopping the server")
+	logging.Infof("Stopping the server")
+	healthSrv.Shutdown()
+	if webSrv != nil {
+		webSrv.Close()
+	}
+	if adminSrv != nil {
+		adminSrv.Stop()
+	}
+	if adminHTTP != nil {
+		adminHTTP.Close()
+	}
 	s.Stop()
-	fmt.Println("Closing the listener")
+	logging.Infof("Closing the listener")
 	listen.Close()
-	fmt.Println("Closing MongoDB connection")
+	logging.Infof("Closing MongoDB connection")
 	client.Disconnect(context.TODO())
-	fmt.Println("End of Program")
+	logging.Infof("End of Program")
 }
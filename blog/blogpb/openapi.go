@@ -0,0 +1,9 @@
+package blogpb
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI document of the BlogService, blog.swagger.json,
+// embedded so that the gateway does not depend on its working directory.
+//
+//go:embed blog.swagger.json
+var OpenAPI []byte
@@ -0,0 +1,196 @@
+// Package client provides a typed Go client for the BlogService.
+//
+// It wraps blogpb.BlogServiceClient with dial configuration (TLS, bearer
+// token auth, timeouts), typed errors, retries for idempotent calls and an
+// iterator over ListBlogs, so callers don't need to deal with raw statuses or
+// the streaming loop.
+package client
+
+import (
+	"context"
+	field_mask "google.golang.org/genproto/protobuf/field_mask"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"grpc-go-course/blog/blogpb"
+	"io"
+)
+
+// Client is a BlogService client. It is safe for concurrent use.
+type Client struct {
+	cc   *grpc.ClientConn
+	rpc  blogpb.BlogServiceClient
+	opts options
+}
+
+// Dial connects to the BlogService at target and returns a Client for it.
+// Without WithTLS the connection is made in plaintext.
+func Dial(ctx context.Context, target string, opts ...Option) (*Client, error) {
+	o := newOptions(opts)
+
+	dialOpts := []grpc.DialOption{grpc.WithInsecure()}
+	if o.caFile != "" {
+		creds, err := credentials.NewClientTLSFromFile(o.caFile, o.serverName)
+		if err != nil {
+			return nil, err
+		}
+		dialOpts = []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	}
+	if o.token != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(tokenAuth{
+			token:  o.token,
+			secure: o.caFile != "",
+		}))
+	}
+	if o.dialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.dialTimeout)
+		defer cancel()
+		dialOpts = append(dialOpts, grpc.WithBlock())
+	}
+	dialOpts = append(dialOpts, o.dialOpts...)
+
+	cc, err := grpc.DialContext(ctx, target, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{cc: cc, rpc: blogpb.NewBlogServiceClient(cc), opts: o}, nil
+}
+
+// New returns a Client that uses an existing BlogService client. Dial related
+// options are ignored, and Close does nothing; the caller owns the connection.
+func New(rpc blogpb.BlogServiceClient, opts ...Option) *Client {
+	return &Client{rpc: rpc, opts: newOptions(opts)}
+}
+
+// Close closes the underlying connection if it was opened by Dial.
+func (c *Client) Close() error {
+	if c.cc == nil {
+		return nil
+	}
+	return c.cc.Close()
+}
+
+// Create stores a new blog and returns it with its server assigned ID.
+// Create is not idempotent, so it is never retried.
+func (c *Client) Create(ctx context.Context, blog *blogpb.Blog) (*blogpb.Blog, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	res, err := c.rpc.CreateBlog(ctx, &blogpb.CreateBlogRequest{Blog: blog})
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return res.GetBlog(), nil
+}
+
+// Get returns the blog with the given ID, or an error matching ErrNotFound.
+func (c *Client) Get(ctx context.Context, id string) (*blogpb.Blog, error) {
+	var blog *blogpb.Blog
+	err := c.retry(ctx, func() error {
+		ctx, cancel := c.withTimeout(ctx)
+		defer cancel()
+		res, err := c.rpc.ReadBlog(ctx, &blogpb.ReadBlogRequest{BlogId: id})
+		blog = res.GetBlog()
+		return err
+	})
+	return blog, err
+}
+
+// Update replaces the blog identified by blog.Id and returns the stored blog.
+func (c *Client) Update(ctx context.Context, blog *blogpb.Blog) (*blogpb.Blog, error) {
+	var updated *blogpb.Blog
+	err := c.retry(ctx, func() error {
+		ctx, cancel := c.withTimeout(ctx)
+		defer cancel()
+		res, err := c.rpc.UpdateBlog(ctx, &blogpb.UpdateBlogRequest{Blog: blog})
+		updated = res.GetBlog()
+		return err
+	})
+	return updated, err
+}
+
+// Patch sets the fields of the blog identified by blog.Id named in paths,
+// such as "title", to their value in blog, leaving the others as they are,
+// and returns the stored blog.
+func (c *Client) Patch(ctx context.Context, blog *blogpb.Blog, paths ...string) (*blogpb.Blog, error) {
+	req := &blogpb.UpdateBlogRequest{Blog: blog, UpdateMask: &field_mask.FieldMask{Paths: paths}}
+	var updated *blogpb.Blog
+	err := c.retry(ctx, func() error {
+		ctx, cancel := c.withTimeout(ctx)
+		defer cancel()
+		res, err := c.rpc.UpdateBlog(ctx, req)
+		updated = res.GetBlog()
+		return err
+	})
+	return updated, err
+}
+
+// Delete removes the blog with the given ID and returns what was deleted, or
+// an error matching ErrNotFound if there is no such blog. It is not retried:
+// if the response to a successful delete were lost, the retry would fail with
+// ErrNotFound.
+func (c *Client) Delete(ctx context.Context, id string) (*blogpb.Blog, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	res, err := c.rpc.DeleteBlog(ctx, &blogpb.DeleteBlogRequest{BlogId: id})
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return res.GetBlog(), nil
+}
+
+// List returns an iterator over all blogs. The configured call timeout, if
+// any, bounds the whole stream rather than each message.
+func (c *Client) List(ctx context.Context) *BlogIterator {
+	ctx, cancel := c.withTimeout(ctx)
+	it := &BlogIterator{cancel: cancel}
+	// The stream is retried only up to its first message: after that, blogs
+	// have been handed to the caller and a retry would repeat them.
+	err := c.retry(ctx, func() error {
+		stream, err := c.rpc.ListBlogs(ctx, &blogpb.ListBlogsRequest{})
+		if err != nil {
+			return err
+		}
+		res, err := stream.Recv()
+		if err == io.EOF {
+			it.done = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		it.stream = stream
+		it.pending = res.GetBlog()
+		return nil
+	})
+	if err != nil {
+		it.err = err
+		it.done = true
+	}
+	if it.done {
+		cancel()
+	}
+	return it
+}
+
+// withTimeout applies the configured per-call timeout to ctx.
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.opts.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.opts.timeout)
+}
+
+// tokenAuth sends a bearer token with every RPC.
+type tokenAuth struct {
+	token  string
+	secure bool
+}
+
+func (t tokenAuth) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + t.token}, nil
+}
+
+func (t tokenAuth) RequireTransportSecurity() bool {
+	return t.secure
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Sentinel errors that errors returned by Client match with errors.Is.
+// Cancelled and expired calls match context.Canceled and
+// context.DeadlineExceeded respectively.
+var (
+	ErrNotFound         = errors.New("blog not found")
+	ErrInvalidArgument  = errors.New("invalid argument")
+	ErrUnauthenticated  = errors.New("unauthenticated")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrUnavailable      = errors.New("blog service unavailable")
+)
+
+// Error is returned for failed RPCs. It keeps the original gRPC status so
+// status.FromError and status.Code keep working on it.
+type Error struct {
+	Code    codes.Code
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Code.String() + ": " + e.Message
+}
+
+// Unwrap returns the sentinel error matching the status code, if any.
+func (e *Error) Unwrap() error {
+	switch e.Code {
+	case codes.NotFound:
+		return ErrNotFound
+	case codes.InvalidArgument:
+		return ErrInvalidArgument
+	case codes.Unauthenticated:
+		return ErrUnauthenticated
+	case codes.PermissionDenied:
+		return ErrPermissionDenied
+	case codes.Unavailable:
+		return ErrUnavailable
+	case codes.Canceled:
+		return context.Canceled
+	case codes.DeadlineExceeded:
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+// GRPCStatus returns the status the error was built from.
+func (e *Error) GRPCStatus() *status.Status {
+	return status.New(e.Code, e.Message)
+}
+
+// convertError turns an RPC error into an *Error.
+func convertError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+	st := status.Convert(err)
+	return &Error{Code: st.Code(), Message: st.Message()}
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"grpc-go-course/blog/blogpb"
+	"io"
+)
+
+// BlogIterator iterates over the blogs streamed by ListBlogs. Use it like a
+// bufio.Scanner:
+//
+//	it := c.List(ctx)
+//	defer it.Close()
+//	for it.Next() {
+//		fmt.Println(it.Blog())
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type BlogIterator struct {
+	stream  blogpb.BlogService_ListBlogsClient
+	cancel  context.CancelFunc
+	pending *blogpb.Blog
+	cur     *blogpb.Blog
+	done    bool
+	err     error
+}
+
+// Next advances to the next blog. It returns false at the end of the stream
+// or on error; call Err to tell the two apart.
+func (it *BlogIterator) Next() bool {
+	if it.pending != nil {
+		it.cur, it.pending = it.pending, nil
+		return true
+	}
+	if it.done {
+		it.cur = nil
+		return false
+	}
+	res, err := it.stream.Recv()
+	if err != nil {
+		if err != io.EOF {
+			it.err = convertError(err)
+		}
+		it.Close()
+		it.cur = nil
+		return false
+	}
+	it.cur = res.GetBlog()
+	return true
+}
+
+// Blog returns the blog the iterator is positioned on.
+func (it *BlogIterator) Blog() *blogpb.Blog {
+	return it.cur
+}
+
+// Err returns the error that ended the iteration, if any. Reaching the end of
+// the stream is not an error.
+func (it *BlogIterator) Err() error {
+	return it.err
+}
+
+// Close stops the iteration and releases the stream. It is safe to call more
+// than once, and is called automatically once Next returns false.
+func (it *BlogIterator) Close() {
+	it.done = true
+	it.pending = nil
+	it.cancel()
+}
+
+// All drains the iterator into a slice.
+func (it *BlogIterator) All() ([]*blogpb.Blog, error) {
+	defer it.Close()
+	var blogs []*blogpb.Blog
+	for it.Next() {
+		blogs = append(blogs, it.Blog())
+	}
+	return blogs, it.Err()
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"google.golang.org/grpc"
+	"time"
+)
+
+// Option configures a Client.
+type Option func(*options)
+
+type options struct {
+	caFile      string
+	serverName  string
+	token       string
+	timeout     time.Duration
+	dialTimeout time.Duration
+	retry       RetryPolicy
+	dialOpts    []grpc.DialOption
+}
+
+func newOptions(opts []Option) options {
+	o := options{retry: DefaultRetryPolicy}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithTLS enables TLS, trusting the CA certificate in caFile. serverName
+// overrides the name used to verify the server certificate; leave it empty to
+// use the host from the dial target.
+func WithTLS(caFile, serverName string) Option {
+	return func(o *options) {
+		o.caFile = caFile
+		o.serverName = serverName
+	}
+}
+
+// WithToken sends token as a bearer token in the authorization metadata of
+// every RPC.
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithTimeout bounds every call. For unary calls it applies to each attempt,
+// for List it applies to the whole stream.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithDialTimeout makes Dial block until the connection is ready or d has
+// elapsed.
+func WithDialTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.dialTimeout = d
+	}
+}
+
+// WithRetry replaces DefaultRetryPolicy. Use NoRetry to disable retries.
+func WithRetry(p RetryPolicy) Option {
+	return func(o *options) {
+		o.retry = p
+	}
+}
+
+// WithDialOptions passes extra options to grpc.DialContext.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOpts = append(o.dialOpts, opts...)
+	}
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy controls how idempotent calls (Get, Update, Patch and the start
+// of List) are retried. Create and Delete are never retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. Later delays grow
+	// by BackoffMultiplier up to MaxBackoff, and are jittered.
+	InitialBackoff    time.Duration
+	MaxBackoff        time.Duration
+	BackoffMultiplier float64
+	// RetryableCodes lists the status codes that trigger a retry.
+	RetryableCodes []codes.Code
+}
+
+// DefaultRetryPolicy retries calls that failed with Unavailable up to three
+// attempts in total.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:       3,
+	InitialBackoff:    100 * time.Millisecond,
+	MaxBackoff:        2 * time.Second,
+	BackoffMultiplier: 2,
+	RetryableCodes:    []codes.Code{codes.Unavailable},
+}
+
+// NoRetry disables retries.
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+func (p RetryPolicy) retryable(err error) bool {
+	code := status.Code(err)
+	for _, c := range p.RetryableCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// retry runs fn until it succeeds, fails with a non retryable error, runs out
+// of attempts or ctx is done. The returned error is converted to *Error.
+func (c *Client) retry(ctx context.Context, fn func() error) error {
+	p := c.opts.retry
+	backoff := p.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || !p.retryable(err) {
+			return convertError(err)
+		}
+
+		delay := backoff
+		if delay > 0 {
+			// Jitter by +/-20% so that clients don't retry in lockstep.
+			delay = time.Duration(float64(delay) * (0.8 + 0.4*rand.Float64()))
+		}
+		select {
+		case <-ctx.Done():
+			return convertError(err)
+		case <-time.After(delay):
+		}
+
+		backoff = time.Duration(float64(backoff) * p.BackoffMultiplier)
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
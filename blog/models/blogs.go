@@ -2,30 +2,31 @@ package models
 
 import (
 	"context"
+	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func ListAll(coll *mongo.Collection) ([]BlogItem, error) {
-	opts := options.Find().SetSort(bson.D{{"author_id", 1}})
-	cursor, err := coll.Find(context.Background(), bson.D{}, opts)
+func ListAll(ctx context.Context, coll *mongo.Collection) ([]BlogItem, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "author_id", Value: 1}})
+	cursor, err := coll.Find(ctx, bson.D{}, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	var results []BlogItem
-	if err = cursor.All(context.Background(), &results); err != nil {
+	if err = cursor.All(ctx, &results); err != nil {
 		return nil, err
 	}
 	return results, nil
 }
 
-func (item *BlogItem) Update(coll *mongo.Collection) (*BlogItem, error) {
+func (item *BlogItem) Update(ctx context.Context, coll *mongo.Collection) (*BlogItem, error) {
 	opts := options.FindOneAndReplace().SetUpsert(false)
 	filter := bson.M{"_id": item.ID}
-	res := coll.FindOneAndReplace(context.Background(), filter, item, opts)
+	res := coll.FindOneAndReplace(ctx, filter, item, opts)
 	if res.Err() != nil {
 		return nil, res.Err()
 	}
@@ -33,12 +34,34 @@ func (item *BlogItem) Update(coll *mongo.Collection) (*BlogItem, error) {
 	return item, nil
 }
 
-func (item *BlogItem) Create(coll *mongo.Collection) (*mongo.InsertOneResult, error) {
-	return coll.InsertOne(context.Background(), item)
+// Patch sets only the given fields (by bson name) of the stored blog to their
+// values in item, then loads the whole updated blog into item.
+func (item *BlogItem) Patch(ctx context.Context, coll *mongo.Collection, fields []string) (*BlogItem, error) {
+	values := bson.M{"author_id": item.AuthorID, "content": item.Content, "title": item.Title}
+	set := bson.M{}
+	for _, f := range fields {
+		v, ok := values[f]
+		if !ok {
+			return nil, fmt.Errorf("unknown blog field %q", f)
+		}
+		set[f] = v
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(false).SetReturnDocument(options.After)
+	filter := bson.M{"_id": item.ID}
+	res := coll.FindOneAndUpdate(ctx, filter, bson.M{"$set": set}, opts)
+	if err := res.Decode(item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (item *BlogItem) Create(ctx context.Context, coll *mongo.Collection) (*mongo.InsertOneResult, error) {
+	return coll.InsertOne(ctx, item)
 }
-func (item *BlogItem) Delete(coll *mongo.Collection) (*mongo.DeleteResult, error) {
-	filter := bson.D{{"_id", item.ID}}
-	res, err := coll.DeleteOne(context.Background(), filter)
+func (item *BlogItem) Delete(ctx context.Context, coll *mongo.Collection) (*mongo.DeleteResult, error) {
+	filter := bson.D{{Key: "_id", Value: item.ID}}
+	res, err := coll.DeleteOne(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -46,9 +69,9 @@ func (item *BlogItem) Delete(coll *mongo.Collection) (*mongo.DeleteResult, error
 
 }
 
-func (item *BlogItem) ById(coll *mongo.Collection) (*mongo.SingleResult, error) {
+func (item *BlogItem) ById(ctx context.Context, coll *mongo.Collection) (*mongo.SingleResult, error) {
 	filter := bson.M{"_id": item.ID}
-	res := coll.FindOne(context.Background(), filter)
+	res := coll.FindOne(ctx, filter)
 	err := res.Decode(item)
 	if err != nil {
 		return nil, err
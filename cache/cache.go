@@ -0,0 +1,210 @@
+// Package cache provides server interceptors memoizing the responses of pure
+// RPCs, whose response only depends on the request, in an LRU cache.
+package cache
+
+import (
+	"context"
+	"expvar"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"strings"
+	"time"
+)
+
+// maxStreamMessages bounds the responses of a streaming RPC worth caching.
+const maxStreamMessages = 1000
+
+// stats are the metrics of the cache, served by expvar at /debug/vars:
+// hits, misses and bypasses per method, responses too large to cache per
+// method, evictions, entries and their size in bytes.
+var stats = expvar.NewMap("cache")
+
+// Cache provides the interceptors memoizing RPCs.
+type Cache struct {
+	lru *LRU
+	// methods maps the full name of the cached methods, such as
+	// "/calculator.CalculatorService/SquareRoot", to an empty request.
+	methods map[string]proto.Message
+}
+
+// New returns a Cache of at most size responses and maxBytes bytes of
+// requests and responses, as marshaled, each kept for ttl, for the given
+// methods, unary or server streaming. Responses too large to fit are not
+// cached. methods maps the full name of each method to a message of the type
+// of its requests, which must only be read.
+func New(size, maxBytes int, ttl time.Duration, methods map[string]proto.Message) *Cache {
+	c := &Cache{lru: NewLRU(size, maxBytes, ttl), methods: methods}
+	c.lru.onEvict = func() { stats.Add("evictions", 1) }
+	stats.Set("entries", expvar.Func(func() interface{} { return c.lru.Len() }))
+	stats.Set("bytes", expvar.Func(func() interface{} { return c.lru.Bytes() }))
+	return c
+}
+
+// ServerOptions returns the interceptors serving cached responses.
+func (c *Cache) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(c.unaryInterceptor),
+		grpc.ChainStreamInterceptor(c.streamInterceptor),
+	}
+}
+
+// Cache-Control values understood in the request metadata: no-cache computes
+// a fresh response and caches it, no-store also leaves the cache alone.
+const (
+	noCache = "no-cache"
+	noStore = "no-store"
+)
+
+// policy returns whether an RPC may be answered from the cache, and whether
+// its response may be cached, according to its cache-control metadata. The
+// REST gateway forwards the Cache-Control header as grpcgateway-cache-control.
+func policy(ctx context.Context) (lookup, store bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	lookup, store = true, true
+	for _, key := range []string{"cache-control", "grpcgateway-cache-control"} {
+		for _, v := range md.Get(key) {
+			for _, directive := range strings.Split(v, ",") {
+				switch strings.ToLower(strings.TrimSpace(directive)) {
+				case noCache:
+					lookup = false
+				case noStore:
+					lookup, store = false, false
+				}
+			}
+		}
+	}
+	return lookup, store
+}
+
+// key identifies the response to req. Deterministic marshaling gives equal
+// requests the same bytes.
+func key(method string, req interface{}) (string, bool) {
+	m, ok := req.(proto.Message)
+	if !ok {
+		return "", false
+	}
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		return "", false
+	}
+	return method + "\x00" + string(b), true
+}
+
+func (c *Cache) lookup(method, k string, lookup bool) (interface{}, bool) {
+	if !lookup {
+		stats.Add("bypasses "+method, 1)
+		return nil, false
+	}
+	v, ok := c.lru.Get(k)
+	if ok {
+		stats.Add("hits "+method, 1)
+	} else {
+		stats.Add("misses "+method, 1)
+	}
+	return v, ok
+}
+
+func (c *Cache) add(method, k string, v interface{}, size int) {
+	if !c.lru.Add(k, v, size) {
+		stats.Add("too large "+method, 1)
+	}
+}
+
+func (c *Cache) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if _, ok := c.methods[info.FullMethod]; !ok {
+		return handler(ctx, req)
+	}
+	k, ok := key(info.FullMethod, req)
+	if !ok {
+		return handler(ctx, req)
+	}
+	lookup, store := policy(ctx)
+	if v, ok := c.lookup(info.FullMethod, k, lookup); ok {
+		return proto.Clone(v.(proto.Message)), nil
+	}
+
+	res, err := handler(ctx, req)
+	if err == nil && store {
+		if m, ok := res.(proto.Message); ok {
+			c.add(info.FullMethod, k, proto.Clone(m), proto.Size(m))
+		}
+	}
+	return res, err
+}
+
+func (c *Cache) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	empty, ok := c.methods[info.FullMethod]
+	if !ok || info.IsClientStream {
+		return handler(srv, ss)
+	}
+	// The request of a server streaming RPC is read here, to look it up,
+	// and handed over to the handler on a miss.
+	req := empty.ProtoReflect().New().Interface()
+	if err := ss.RecvMsg(req); err != nil {
+		return err
+	}
+	k, ok := key(info.FullMethod, req)
+	if !ok {
+		return handler(srv, &cachingStream{ServerStream: ss, req: req})
+	}
+	lookup, store := policy(ss.Context())
+	if v, ok := c.lookup(info.FullMethod, k, lookup); ok {
+		for _, m := range v.([]proto.Message) {
+			if err := ss.SendMsg(m); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	cs := &cachingStream{ServerStream: ss, req: req, maxBytes: c.lru.maxBytes - len(k)}
+	err := handler(srv, cs)
+	if err == nil && store {
+		if cs.overflow {
+			stats.Add("too large "+info.FullMethod, 1)
+		} else {
+			c.add(info.FullMethod, k, cs.sent, cs.bytes)
+		}
+	}
+	return err
+}
+
+// cachingStream replays the request read by the interceptor, and keeps the
+// responses sent, until they are too many or too large to be cached.
+type cachingStream struct {
+	grpc.ServerStream
+	req      proto.Message
+	sent     []proto.Message
+	bytes    int
+	maxBytes int
+	overflow bool
+}
+
+func (s *cachingStream) RecvMsg(m interface{}) error {
+	if s.req == nil {
+		return s.ServerStream.RecvMsg(m)
+	}
+	proto.Merge(m.(proto.Message), s.req)
+	s.req = nil
+	return nil
+}
+
+func (s *cachingStream) SendMsg(m interface{}) error {
+	if err := s.ServerStream.SendMsg(m); err != nil {
+		return err
+	}
+	if s.overflow {
+		return nil
+	}
+	pm, ok := m.(proto.Message)
+	if ok {
+		s.bytes += proto.Size(pm)
+	}
+	if !ok || len(s.sent) >= maxStreamMessages || s.bytes > s.maxBytes {
+		s.overflow, s.sent = true, nil
+		return nil
+	}
+	s.sent = append(s.sent, proto.Clone(pm))
+	return nil
+}
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is a cache of at most a fixed number of entries and bytes, evicting the
+// least recently used entries to make room, whose entries expire some time
+// after being added. It is safe for concurrent use.
+type LRU struct {
+	mu       sync.Mutex
+	max      int
+	maxBytes int
+	bytes    int
+	ttl      time.Duration
+	ll       *list.List
+	items    map[string]*list.Element
+	// onEvict, if not nil, is called with the mutex held when an entry is
+	// evicted to make room.
+	onEvict func()
+}
+
+type entry struct {
+	key     string
+	value   interface{}
+	size    int
+	expires time.Time
+}
+
+// NewLRU returns an empty LRU of at most max entries and maxBytes bytes, each
+// entry kept for ttl.
+func NewLRU(max, maxBytes int, ttl time.Duration) *LRU {
+	return &LRU{max: max, maxBytes: maxBytes, ttl: ttl, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// Get returns the value of key, if it is cached and hasn't expired.
+func (c *LRU) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if time.Now().After(e.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return e.value, true
+}
+
+// Add caches value, of size bytes, under key, replacing any previous value.
+// The key counts towards the size of the entry. It reports false, and caches
+// nothing, if the entry is larger than the whole cache.
+func (c *LRU) Add(key string, value interface{}, size int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	size += len(key)
+	if size > c.maxBytes {
+		return false
+	}
+	for c.ll.Len() >= c.max || c.bytes+size > c.maxBytes {
+		c.remove(c.ll.Back())
+		if c.onEvict != nil {
+			c.onEvict()
+		}
+	}
+	c.items[key] = c.ll.PushFront(&entry{key: key, value: value, size: size, expires: time.Now().Add(c.ttl)})
+	c.bytes += size
+	return true
+}
+
+func (c *LRU) remove(el *list.Element) {
+	e := el.Value.(*entry)
+	c.ll.Remove(el)
+	delete(c.items, e.key)
+	c.bytes -= e.size
+}
+
+// Len returns the number of cached entries, expired ones included until they
+// are evicted or looked up.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// Bytes returns the size of the cached entries, expired ones included until
+// they are evicted or looked up.
+func (c *LRU) Bytes() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bytes
+}
@@ -0,0 +1,72 @@
+package cache_test
+
+import (
+	"grpc-go-course/cache"
+	"testing"
+	"time"
+)
+
+func TestLRUBytes(t *testing.T) {
+	// Keys are one byte, so the entries below take 10 bytes each.
+	c := cache.NewLRU(100, 25, time.Hour)
+	for _, k := range []string{"a", "b"} {
+		if !c.Add(k, k, 9) {
+			t.Fatalf("Add(%s) did not fit", k)
+		}
+	}
+	c.Get("a")
+	// c needs room: b, the least recently used, is evicted.
+	if !c.Add("c", "c", 9) {
+		t.Fatal("Add(c) did not fit")
+	}
+	for k, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := c.Get(k); ok != want {
+			t.Errorf("Get(%s) found %v, want %v", k, ok, want)
+		}
+	}
+	if c.Len() != 2 || c.Bytes() != 20 {
+		t.Errorf("the cache holds %d entries of %d bytes, want 2 of 20", c.Len(), c.Bytes())
+	}
+
+	// Replacing an entry accounts for its new size only.
+	if !c.Add("a", "a", 4) || c.Bytes() != 15 {
+		t.Errorf("after replacing a, the cache holds %d bytes, want 15", c.Bytes())
+	}
+
+	// An entry larger than the cache is not cached, and leaves the others.
+	if c.Add("d", "d", 25) {
+		t.Error("Add(d) of 26 bytes fit in 25")
+	}
+	if _, ok := c.Get("d"); ok || c.Len() != 2 || c.Bytes() != 15 {
+		t.Errorf("after a too large entry, the cache holds %d entries of %d bytes, want 2 of 15", c.Len(), c.Bytes())
+	}
+	// Even one replacing a cached entry, which is then gone.
+	if c.Add("c", "c", 30) {
+		t.Error("Add(c) of 31 bytes fit in 25")
+	}
+	if _, ok := c.Get("c"); ok || c.Bytes() != 5 {
+		t.Errorf("the too large c replaced the cached one, leaving %d bytes, want 5", c.Bytes())
+	}
+}
+
+func TestLRUEntries(t *testing.T) {
+	c := cache.NewLRU(2, 1<<20, time.Hour)
+	c.Add("a", 1, 0)
+	c.Add("b", 2, 0)
+	c.Add("c", 3, 0)
+	if _, ok := c.Get("a"); ok || c.Len() != 2 {
+		t.Errorf("the cache holds %d entries, with a: %v, want 2 without a", c.Len(), ok)
+	}
+}
+
+func TestLRUExpiry(t *testing.T) {
+	c := cache.NewLRU(10, 1<<20, time.Millisecond)
+	c.Add("a", 1, 10)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Error("an expired entry was found")
+	}
+	if c.Len() != 0 || c.Bytes() != 0 {
+		t.Errorf("the cache holds %d entries of %d bytes after expiry, want none", c.Len(), c.Bytes())
+	}
+}
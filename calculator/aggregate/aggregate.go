@@ -0,0 +1,86 @@
+// Package aggregate groups a stream of numbers into tumbling or sliding
+// windows, by count or by time, and aggregates each window as it closes.
+package aggregate
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Kind is an aggregation computed over the numbers of a window.
+type Kind int
+
+const (
+	Min Kind = iota + 1
+	Max
+	Sum
+	Mean
+	TopK
+	DistinctCount
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Min:
+		return "min"
+	case Max:
+		return "max"
+	case Sum:
+		return "sum"
+	case Mean:
+		return "mean"
+	case TopK:
+		return "top-k"
+	case DistinctCount:
+		return "distinct count"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Result is the aggregation of a window. Value holds the result of every
+// aggregation but TopK, whose result is Top, largest first.
+type Result struct {
+	Count int
+	Value float64
+	Top   []float64
+}
+
+// Compute aggregates values, which must not be empty. k is the number of
+// values kept by TopK.
+func Compute(kind Kind, k int, values []float64) Result {
+	res := Result{Count: len(values)}
+	switch kind {
+	case Min:
+		res.Value = math.Inf(1)
+		for _, v := range values {
+			res.Value = math.Min(res.Value, v)
+		}
+	case Max:
+		res.Value = math.Inf(-1)
+		for _, v := range values {
+			res.Value = math.Max(res.Value, v)
+		}
+	case Sum, Mean:
+		for _, v := range values {
+			res.Value += v
+		}
+		if kind == Mean {
+			res.Value /= float64(len(values))
+		}
+	case TopK:
+		sorted := append([]float64(nil), values...)
+		sort.Sort(sort.Reverse(sort.Float64Slice(sorted)))
+		if len(sorted) > k {
+			sorted = sorted[:k]
+		}
+		res.Top = sorted
+	case DistinctCount:
+		seen := make(map[float64]struct{}, len(values))
+		for _, v := range values {
+			seen[v] = struct{}{}
+		}
+		res.Value = float64(len(seen))
+	}
+	return res
+}
@@ -0,0 +1,201 @@
+package aggregate_test
+
+import (
+	"grpc-go-course/calculator/aggregate"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestCompute(t *testing.T) {
+	values := []float64{3, -1, 4, 1, 5, 9, 2, 6, 5, 3}
+	tests := []struct {
+		kind aggregate.Kind
+		want aggregate.Result
+	}{
+		{aggregate.Min, aggregate.Result{Count: 10, Value: -1}},
+		{aggregate.Max, aggregate.Result{Count: 10, Value: 9}},
+		{aggregate.Sum, aggregate.Result{Count: 10, Value: 37}},
+		{aggregate.Mean, aggregate.Result{Count: 10, Value: 3.7}},
+		{aggregate.TopK, aggregate.Result{Count: 10, Top: []float64{9, 6, 5}}},
+		{aggregate.DistinctCount, aggregate.Result{Count: 10, Value: 8}},
+	}
+	for _, tt := range tests {
+		if got := aggregate.Compute(tt.kind, 3, values); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Compute(%v) = %+v, want %+v", tt.kind, got, tt.want)
+		}
+	}
+
+	if got := aggregate.Compute(aggregate.TopK, 5, []float64{1, 2}); !reflect.DeepEqual(got.Top, []float64{2, 1}) {
+		t.Errorf("top 5 of 2 numbers = %v, want [2 1]", got.Top)
+	}
+	if values[0] != 3 || values[9] != 3 {
+		t.Errorf("TopK sorted its input: %v", values)
+	}
+}
+
+// sorted returns a sorted copy of values, which windows return in no
+// particular order.
+func sorted(values []float64) []float64 {
+	s := append([]float64(nil), values...)
+	sort.Float64s(s)
+	return s
+}
+
+func TestCountWindow(t *testing.T) {
+	tests := []struct {
+		name        string
+		size, slide int
+		n           int
+		// want are the windows closed by adding 1 to n, then by the flush.
+		want [][]float64
+	}{
+		{"tumbling", 3, 3, 7, [][]float64{{1, 2, 3}, {4, 5, 6}, {7}}},
+		{"tumbling exact", 3, 3, 6, [][]float64{{1, 2, 3}, {4, 5, 6}}},
+		{"tumbling ring", 2, 2, 5, [][]float64{{1, 2}, {3, 4}, {5}}},
+		{"sliding by one", 3, 1, 5, [][]float64{{1}, {1, 2}, {1, 2, 3}, {2, 3, 4}, {3, 4, 5}}},
+		{"sliding", 4, 2, 7, [][]float64{{1, 2}, {1, 2, 3, 4}, {3, 4, 5, 6}, {7}}},
+		{"sliding ring", 3, 2, 9, [][]float64{{1, 2}, {2, 3, 4}, {4, 5, 6}, {6, 7, 8}, {9}}},
+		{"sliding two flushed", 5, 3, 8, [][]float64{{1, 2, 3}, {2, 3, 4, 5, 6}, {7, 8}}},
+		{"empty", 3, 1, 0, nil},
+	}
+	for _, tt := range tests {
+		w := aggregate.NewCountWindow(tt.size, tt.slide)
+		var got [][]float64
+		for i := 1; i <= tt.n; i++ {
+			if values, ok := w.Add(float64(i)); ok {
+				got = append(got, sorted(values))
+			}
+		}
+		if values, ok := w.Flush(); ok {
+			got = append(got, sorted(values))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: windows %v, want %v", tt.name, got, tt.want)
+		}
+		if values, ok := w.Flush(); ok {
+			t.Errorf("%s: a second flush returned %v", tt.name, values)
+		}
+	}
+}
+
+type timeWindow struct {
+	start, end time.Duration
+	values     []float64
+}
+
+func TestTimeWindow(t *testing.T) {
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return t0.Add(d * time.Second) }
+
+	tests := []struct {
+		name        string
+		size, slide time.Duration
+		// events are the times numbers arrive at, with the number i+1 at
+		// events[i]; the windows are closed as time passes their end, and
+		// flushed at the end of the stream, at flush.
+		events []time.Duration
+		flush  time.Duration
+		want   []timeWindow
+	}{
+		{
+			name: "tumbling", size: 10, slide: 10,
+			events: []time.Duration{1, 5, 12, 19, 34},
+			flush:  36,
+			want: []timeWindow{
+				{0, 10, []float64{1, 2}},
+				{10, 20, []float64{3, 4}},
+				{20, 30, nil},
+				{30, 36, []float64{5}},
+			},
+		},
+		{
+			name: "tumbling exact", size: 10, slide: 10,
+			events: []time.Duration{1, 5},
+			flush:  10,
+			want:   []timeWindow{{0, 10, []float64{1, 2}}},
+		},
+		{
+			// A number at the end of a window belongs to the next one.
+			name: "boundary", size: 10, slide: 10,
+			events: []time.Duration{0, 10},
+			flush:  15,
+			want: []timeWindow{
+				{0, 10, []float64{1}},
+				{10, 15, []float64{2}},
+			},
+		},
+		{
+			name: "sliding", size: 10, slide: 5,
+			events: []time.Duration{1, 4, 6, 9, 12, 16},
+			flush:  17,
+			want: []timeWindow{
+				{-5, 5, []float64{1, 2}},
+				{0, 10, []float64{1, 2, 3, 4}},
+				{5, 15, []float64{3, 4, 5}},
+				// Only the number after the last window is flushed.
+				{15, 17, []float64{6}},
+			},
+		},
+		{
+			name: "sliding exact", size: 10, slide: 5,
+			events: []time.Duration{1, 6},
+			flush:  10,
+			want: []timeWindow{
+				{-5, 5, []float64{1}},
+				{0, 10, []float64{1, 2}},
+			},
+		},
+		{
+			name: "empty", size: 10, slide: 5,
+			flush: 3,
+		},
+	}
+	for _, tt := range tests {
+		w := aggregate.NewTimeWindow(t0, tt.size*time.Second, tt.slide*time.Second)
+		var got []timeWindow
+		record := func(start, end time.Time, values []float64) {
+			got = append(got, timeWindow{start.Sub(t0) / time.Second, end.Sub(t0) / time.Second, values})
+		}
+		closeUntil := func(now time.Time) {
+			for !now.Before(w.End()) {
+				record(w.Close())
+			}
+		}
+		for i, e := range tt.events {
+			closeUntil(at(e))
+			w.Add(at(e), float64(i+1))
+		}
+		closeUntil(at(tt.flush))
+		if start, end, values, ok := w.Flush(at(tt.flush)); ok {
+			record(start, end, values)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: windows %v, want %v", tt.name, got, tt.want)
+		}
+		if _, _, values, ok := w.Flush(at(tt.flush)); ok {
+			t.Errorf("%s: a second flush returned %v", tt.name, values)
+		}
+	}
+}
+
+// TestTimeWindowLateClose checks that numbers which arrive after the end of
+// a window, before it gets closed, are left out of it and kept for the next.
+func TestTimeWindowLateClose(t *testing.T) {
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	w := aggregate.NewTimeWindow(t0, 10*time.Second, 10*time.Second)
+	w.Add(t0.Add(time.Second), 1)
+	w.Add(t0.Add(12*time.Second), 2)
+
+	if _, _, values := w.Close(); !reflect.DeepEqual(values, []float64{1}) {
+		t.Errorf("the first window has %v, want [1]", values)
+	}
+	if w.Len() != 1 {
+		t.Errorf("the window holds %d numbers after the first closed, want 1", w.Len())
+	}
+	_, _, values, ok := w.Flush(t0.Add(15 * time.Second))
+	if !ok || !reflect.DeepEqual(values, []float64{2}) {
+		t.Errorf("Flush = %v, %v, want [2], true", values, ok)
+	}
+}
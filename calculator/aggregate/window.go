@@ -0,0 +1,137 @@
+package aggregate
+
+import (
+	"time"
+)
+
+// CountWindow groups numbers into windows of the last Size numbers, closing
+// one every Slide numbers. Slide equal to Size makes tumbling windows.
+type CountWindow struct {
+	size, slide int
+	// buf is a ring buffer of the last size numbers, next the index the
+	// following number goes to once it is full.
+	buf     []float64
+	next    int
+	pending int
+}
+
+// NewCountWindow returns a CountWindow. size and slide must be positive, and
+// slide at most size.
+func NewCountWindow(size, slide int) *CountWindow {
+	return &CountWindow{size: size, slide: slide}
+}
+
+// Add adds x and returns the numbers of the window it closes, if any, in no
+// particular order. They are only valid until the next call.
+func (w *CountWindow) Add(x float64) ([]float64, bool) {
+	if len(w.buf) < w.size {
+		w.buf = append(w.buf, x)
+	} else {
+		w.buf[w.next] = x
+		w.next = (w.next + 1) % w.size
+	}
+	w.pending++
+	if w.pending < w.slide {
+		return nil, false
+	}
+	w.pending = 0
+	return w.buf, true
+}
+
+// Flush closes the current window at the end of the stream, returning the
+// numbers that arrived since the last window closed, if any. Those a sliding
+// window already reported are left out.
+func (w *CountWindow) Flush() ([]float64, bool) {
+	if w.pending == 0 {
+		return nil, false
+	}
+	n := w.pending
+	w.pending = 0
+	if len(w.buf) < w.size {
+		return w.buf[len(w.buf)-n:], true
+	}
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = w.buf[(w.next-n+i+w.size)%w.size]
+	}
+	return values, true
+}
+
+type timedValue struct {
+	at    time.Time
+	value float64
+}
+
+// TimeWindow groups numbers into windows covering the last Size of time,
+// closing one every Slide from the start of the stream. Slide equal to Size
+// makes tumbling windows.
+type TimeWindow struct {
+	size, slide time.Duration
+	end         time.Time
+	buf         []timedValue
+	pending     int
+}
+
+// NewTimeWindow returns a TimeWindow whose first window closes at
+// start+slide. size and slide must be positive, and slide at most size.
+func NewTimeWindow(start time.Time, size, slide time.Duration) *TimeWindow {
+	return &TimeWindow{size: size, slide: slide, end: start.Add(slide)}
+}
+
+// Add adds x, received at t.
+func (w *TimeWindow) Add(t time.Time, x float64) {
+	w.buf = append(w.buf, timedValue{at: t, value: x})
+	w.pending++
+}
+
+// Len returns how many numbers the window holds.
+func (w *TimeWindow) Len() int {
+	return len(w.buf)
+}
+
+// End returns when the current window closes.
+func (w *TimeWindow) End() time.Time {
+	return w.end
+}
+
+// Close closes the current window, returning its bounds and numbers, which
+// may be empty, and moves on to the next window.
+func (w *TimeWindow) Close() (start, end time.Time, values []float64) {
+	start, end = w.end.Add(-w.size), w.end
+	values = w.between(start, end)
+	// Numbers received after end, before the window got closed, belong to
+	// the next window only.
+	w.pending = len(w.between(end, end.Add(w.size)))
+
+	w.end = w.end.Add(w.slide)
+	keepFrom := w.end.Add(-w.size)
+	i := 0
+	for i < len(w.buf) && w.buf[i].at.Before(keepFrom) {
+		i++
+	}
+	w.buf = append(w.buf[:0], w.buf[i:]...)
+	return start, end, values
+}
+
+// Flush closes the current window early, at now, at the end of the stream.
+// The window starts where the last one ended, so that numbers a sliding
+// window already reported are left out. It returns false if no number
+// arrived since the last window closed.
+func (w *TimeWindow) Flush(now time.Time) (start, end time.Time, values []float64, ok bool) {
+	if w.pending == 0 {
+		return start, end, nil, false
+	}
+	w.pending = 0
+	start = w.end.Add(-w.slide)
+	return start, now, w.between(start, now.Add(1)), true
+}
+
+func (w *TimeWindow) between(start, end time.Time) []float64 {
+	var values []float64
+	for _, tv := range w.buf {
+		if !tv.at.Before(start) && tv.at.Before(end) {
+			values = append(values, tv.value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,63 @@
+// Package bigmath computes functions of big.Float numbers to arbitrary
+// precision.
+package bigmath
+
+import (
+	"math"
+	"math/big"
+)
+
+// Root returns the positive nth root of a, which must not be negative, with
+// prec bits of mantissa. n must be positive.
+func Root(a *big.Float, n int, prec uint) *big.Float {
+	if a.Sign() == 0 || n == 1 {
+		return new(big.Float).SetPrec(prec).Set(a)
+	}
+	// Iterate with extra bits, so that the result is correctly rounded to
+	// prec bits more often than not.
+	work := prec + 32
+	x := new(big.Float).SetPrec(work).Set(a)
+	if n == 2 {
+		return new(big.Float).SetPrec(prec).Sqrt(x)
+	}
+
+	// Newton's method, x' = ((n-1)x + a/x^(n-1)) / n, starting from a guess
+	// good to about 50 bits computed from the exponent and mantissa of a, so
+	// that numbers outside the range of a float64 work too.
+	mant := new(big.Float)
+	exp := a.MantExp(mant)
+	m, _ := mant.Float64()
+	q, r := exp/n, exp%n
+	guess := math.Pow(m*math.Pow(2, float64(r)), 1/float64(n))
+	x.SetFloat64(guess).SetMantExp(x, q)
+
+	bn := new(big.Float).SetPrec(work).SetInt64(int64(n))
+	bn1 := new(big.Float).SetPrec(work).SetInt64(int64(n - 1))
+	pow, t, prev := new(big.Float).SetPrec(work), new(big.Float).SetPrec(work), new(big.Float).SetPrec(work)
+	for i := 0; i < 100; i++ {
+		prev.Set(x)
+		ipow(pow, x, n-1)
+		t.Quo(a, pow)
+		x.Mul(x, bn1).Add(x, t).Quo(x, bn)
+		// Newton's method doubles the correct bits every step; stop once
+		// a step changes x by less than its last bit.
+		t.Sub(x, prev)
+		if t.Sign() == 0 || t.MantExp(nil)-x.MantExp(nil) < -int(work) {
+			break
+		}
+	}
+	return new(big.Float).SetPrec(prec).Set(x)
+}
+
+// ipow sets z to x^n for n >= 1.
+func ipow(z, x *big.Float, n int) {
+	base := new(big.Float).SetPrec(z.Prec()).Set(x)
+	z.SetInt64(1)
+	for n > 0 {
+		if n&1 == 1 {
+			z.Mul(z, base)
+		}
+		base.Mul(base, base)
+		n >>= 1
+	}
+}
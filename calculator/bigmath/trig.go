@@ -0,0 +1,74 @@
+package bigmath
+
+import (
+	"math/big"
+)
+
+// Pi returns π with prec bits of mantissa.
+func Pi(prec uint) *big.Float {
+	// Machin's formula, π = 16 atan(1/5) - 4 atan(1/239).
+	work := prec + 32
+	pi := new(big.Float).SetPrec(work).Mul(atanInv(5, work), big.NewFloat(16))
+	pi.Sub(pi, new(big.Float).SetPrec(work).Mul(atanInv(239, work), big.NewFloat(4)))
+	return new(big.Float).SetPrec(prec).Set(pi)
+}
+
+// atanInv returns atan(1/k) for k > 1, with prec bits of mantissa, from the
+// series 1/k - 1/(3k^3) + 1/(5k^5) - ...
+func atanInv(k int64, prec uint) *big.Float {
+	k2 := new(big.Float).SetPrec(prec).SetInt64(k * k)
+	pow := new(big.Float).SetPrec(prec).Quo(big.NewFloat(1), new(big.Float).SetPrec(prec).SetInt64(k))
+	sum := new(big.Float).SetPrec(prec).Set(pow)
+	t := new(big.Float).SetPrec(prec)
+	for i := int64(1); ; i++ {
+		pow.Quo(pow, k2)
+		if pow.MantExp(nil) < -int(prec) {
+			return sum
+		}
+		t.Quo(pow, t.SetInt64(2*i+1))
+		if i%2 == 1 {
+			sum.Sub(sum, t)
+		} else {
+			sum.Add(sum, t)
+		}
+	}
+}
+
+// SinCos returns the sine and cosine of x with prec bits of mantissa. The
+// Taylor series it sums converge quickly for |x| up to about π, and ever
+// more slowly beyond.
+func SinCos(x *big.Float, prec uint) (sin, cos *big.Float) {
+	work := prec + 32
+	sin = new(big.Float).SetPrec(work)
+	cos = new(big.Float).SetPrec(work).SetInt64(1)
+	// term is x^k / k!; sin gets the odd terms and cos the even ones, with
+	// alternating signs.
+	term := new(big.Float).SetPrec(work).SetInt64(1)
+	d := new(big.Float).SetPrec(work)
+	for k := int64(1); ; k++ {
+		term.Mul(term, x).Quo(term, d.SetInt64(k))
+		switch k % 4 {
+		case 1:
+			sin.Add(sin, term)
+		case 2:
+			cos.Sub(cos, term)
+		case 3:
+			sin.Sub(sin, term)
+		case 0:
+			cos.Add(cos, term)
+		}
+		if term.Sign() == 0 || k > 1 && term.MantExp(nil) < minExp(sin, cos)-int(work) {
+			break
+		}
+	}
+	return new(big.Float).SetPrec(prec).Set(sin), new(big.Float).SetPrec(prec).Set(cos)
+}
+
+// minExp returns the smallest binary exponent of x and y.
+func minExp(x, y *big.Float) int {
+	ex, ey := x.MantExp(nil), y.MantExp(nil)
+	if ex < ey {
+		return ex
+	}
+	return ey
+}
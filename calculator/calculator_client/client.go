@@ -1,145 +1,199 @@
+// Command calculator_client calls the CalculatorService from the command
+// line, one subcommand per RPC.
+//
+// Usage:
+//
+//	calculator_client [global flags] <command> [args]
+//
+// Commands:
+//
+//	sum <a> <b>           Sum
+//	bigsum <numbers...>   BigSum, exact sum of decimal numbers
+//	bigsub <a> <b>        BigSubtract
+//	bigmul <numbers...>   BigMultiply
+//	bigdiv <a> <b> [scale]
+//	                      BigDivide, rounding to scale digits if needed
+//	sqrt <n>              SquareRoot
+//	root <n> [degree] [precision] [complex]
+//	                      Root, the square root by default; complex allows
+//	                      complex roots of negative numbers
+//	convert <value> <from> <to>
+//	                      Convert, e.g. convert 100 degC degF
+//	eval <expr> [name=value...]
+//	                      Evaluate, binding the given variables
+//	decompose <n>         DecomposePrimeNumber, printing factors as they arrive
+//	isprime <n>           IsPrime
+//	primes <start> <end>  GeneratePrimes, printing one prime per line
+//	gcd <numbers...>      Gcd
+//	lcm <numbers...>      Lcm
+//	modpow <base> <exponent> <modulus>
+//	                      ModPow
+//	modinv <n> <modulus>  ModInverse
+//	factorial <n>         Factorial
+//	binomial <n> <k>      Binomial
+//	average [numbers...]  ComputeAverage
+//	stats [numbers...]    ComputeStatistics
+//	max [numbers...]      FindMaximum, printing each new maximum as it arrives
+//	aggregate <min|max|sum|mean|distinct|top=K> <size> [/slide] [numbers...]
+//	                      StreamAggregate over windows of size numbers, or of
+//	                      a duration such as 5s, printing each window's result
+//	session [id]          Session, an RPN calculator reading commands such as
+//	                      "3 4 + sto x", "pop" or "rcl x" from stdin, resuming
+//	                      the session with the given ID if any
+//	factorize <n>         StartFactorization, printing the operation name
+//	operation <name>      GetOperation
+//	operations [filter]   ListOperations, e.g. with the filter done=false
+//	wait <name> [timeout] WaitOperation
+//	cancel <name>         CancelOperation
+//	delete <name>         DeleteOperation
+//	matmul <a> <b>        Multiply
+//	transpose <m>         Transpose
+//	det <m>               Determinant
+//	inverse <m>           Inverse
+//	solve <a> <b>         Solve, X such that a x X = b
+//	history [method=M] [start=T] [end=T] [limit=N]
+//	                      ListHistory, newest first. Times are RFC 3339 or
+//	                      durations before now, such as 1h
+//
+// average, stats, max and aggregate stream numbers from stdin, separated by whitespace,
+// when none are given as arguments.
+//
+// Matrices are given inline as "1,2;3,4", rows separated by semicolons, or as
+// @file with one row per line, @- for stdin. Matrices read from files are
+// streamed row by row with StreamMatrix, without being held in memory.
 package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"google.golang.org/genproto/googleapis/longrunning"
+	"google.golang.org/grpc/metadata"
 	"grpc-go-course/calculator/calculatorpb"
-	"io"
+	"grpc-go-course/dial"
 	"log"
-	"time"
+	"os"
 )
 
-func main() {
-	cc, err := grpc.Dial("localhost:50051", grpc.WithInsecure())
-	if err != nil {
-		log.Fatalf("Could not connect %v", err)
-	}
-
-	defer cc.Close()
-
-	c := calculatorpb.NewCalculatorServiceClient(cc)
-	//doUnary(c)
-	//doServerStream(c)
-	//doClientStream(c)
-	//doBiDiStream(c)
-	doErrorUnary(c)
+// command is a calculator_client subcommand. run, or runOps, runLinalg and
+// runHistory for commands of the Operations, LinearAlgebraService and
+// HistoryService services, receives the arguments following the command
+// name.
+type command struct {
+	name       string
+	usage      string
+	run        func(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error
+	runOps     func(ctx context.Context, c longrunning.OperationsClient, args []string) error
+	runLinalg  func(ctx context.Context, c calculatorpb.LinearAlgebraServiceClient, args []string) error
+	runHistory func(ctx context.Context, c calculatorpb.HistoryServiceClient, args []string) error
 }
 
-func doErrorUnary(c calculatorpb.CalculatorServiceClient) {
-	doErrorCall(c, 10)
-	doErrorCall(c, -20)
+var commands = []command{
+	{"sum", "sum <a> <b>", runSum, nil, nil, nil},
+	{"bigsum", "bigsum <numbers...>", runBigSum, nil, nil, nil},
+	{"bigsub", "bigsub <a> <b>", runBigSubtract, nil, nil, nil},
+	{"bigmul", "bigmul <numbers...>", runBigMultiply, nil, nil, nil},
+	{"bigdiv", "bigdiv <a> <b> [scale]", runBigDivide, nil, nil, nil},
+	{"sqrt", "sqrt <n>", runSquareRoot, nil, nil, nil},
+	{"root", "root <n> [degree] [precision] [complex]", runRoot, nil, nil, nil},
+	{"convert", "convert <value> <from> <to>", runConvert, nil, nil, nil},
+	{"eval", "eval <expr> [name=value...]", runEvaluate, nil, nil, nil},
+	{"decompose", "decompose <n>", runDecompose, nil, nil, nil},
+	{"isprime", "isprime <n>", runIsPrime, nil, nil, nil},
+	{"primes", "primes <start> <end>", runGeneratePrimes, nil, nil, nil},
+	{"gcd", "gcd <numbers...>", runGcd, nil, nil, nil},
+	{"lcm", "lcm <numbers...>", runLcm, nil, nil, nil},
+	{"modpow", "modpow <base> <exponent> <modulus>", runModPow, nil, nil, nil},
+	{"modinv", "modinv <n> <modulus>", runModInverse, nil, nil, nil},
+	{"factorial", "factorial <n>", runFactorial, nil, nil, nil},
+	{"binomial", "binomial <n> <k>", runBinomial, nil, nil, nil},
+	{"average", "average [numbers...]", runAverage, nil, nil, nil},
+	{"stats", "stats [numbers...]", runStatistics, nil, nil, nil},
+	{"max", "max [numbers...]", runMax, nil, nil, nil},
+	{"aggregate", "aggregate <min|max|sum|mean|distinct|top=K> <size> [/slide] [numbers...]", runAggregate, nil, nil, nil},
+	{"session", "session [id]", runSession, nil, nil, nil},
+	{"factorize", "factorize <n>", runFactorize, nil, nil, nil},
+	{"operation", "operation <name>", nil, runOperation, nil, nil},
+	{"operations", "operations [filter]", nil, runListOperations, nil, nil},
+	{"wait", "wait <name> [timeout]", nil, runWaitOperation, nil, nil},
+	{"cancel", "cancel <name>", nil, runCancelOperation, nil, nil},
+	{"delete", "delete <name>", nil, runDeleteOperation, nil, nil},
+	{"matmul", "matmul <a> <b>", nil, nil, runMultiply, nil},
+	{"transpose", "transpose <m>", nil, nil, runTranspose, nil},
+	{"det", "det <m>", nil, nil, runDeterminant, nil},
+	{"inverse", "inverse <m>", nil, nil, runInverse, nil},
+	{"solve", "solve <a> <b>", nil, nil, runSolve, nil},
+	{"history", "history [method=M] [start=T] [end=T] [limit=N]", nil, nil, nil, runHistory},
 }
 
-func doErrorCall(c calculatorpb.CalculatorServiceClient, n int32) {
-	req := &calculatorpb.SquareRootRequest{Number: n}
-	res, err := c.SquareRoot(context.Background(), req)
-	if err != nil {
-		resErr, ok := status.FromError(err)
-		if ok {
-			// error from gRPC
-			if resErr.Code() == codes.InvalidArgument {
-				fmt.Println(resErr.Message())
-			}
-		} else {
-			log.Fatalf("Error calling SquareRoot: %v", resErr)
-		}
-		return
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("calculator_client: ")
+
+	var df dial.Flags
+	df.Register(flag.CommandLine, dial.Defaults{
+		Addr:      "localhost:50051",
+		CAFile:    "ssl/ca.crt",
+		Plaintext: true,
+	})
+	noCache := flag.Bool("no-cache", false, "ask for a freshly computed response rather than a cached one")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
 	}
-	fmt.Println("SquareRoot: ", res.GetResult())
-}
-
-func doUnary(c calculatorpb.CalculatorServiceClient) {
-	req := &calculatorpb.SumRequest{
-		Num_1: 3,
-		Num_2: 7,
+	cmd, ok := findCommand(flag.Arg(0))
+	if !ok {
+		log.Printf("unknown command %q", flag.Arg(0))
+		usage()
+		os.Exit(2)
 	}
 
-	res, err := c.Sum(context.Background(), req)
+	cc, err := df.Dial(context.Background())
 	if err != nil {
-		log.Fatalf("error while calling Sum RPC: %v", err)
+		log.Fatalf("Could not connect %v", err)
 	}
-	log.Printf("Response from Sum: %v", res.Result)
-}
+	defer cc.Close()
 
-func doServerStream(c calculatorpb.CalculatorServiceClient) {
-	req := &calculatorpb.PrimeNumberDecompositionRequest{Number: 120000300000}
-	stream, err := c.DecomposePrimeNumber(context.Background(), req)
-	if err != nil {
-		log.Fatalf("Failed to stream %v", err)
+	ctx, cancel := df.CallContext(context.Background())
+	defer cancel()
+	if *noCache {
+		ctx = metadata.AppendToOutgoingContext(ctx, "cache-control", "no-cache")
 	}
-
-	for {
-		msg, err := stream.Recv()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			log.Fatalf("Failed to read stream %v", err)
-		}
-		fmt.Println("Prime Num Decomposition: ", msg.GetResult())
+	switch {
+	case cmd.runOps != nil:
+		err = cmd.runOps(ctx, longrunning.NewOperationsClient(cc), flag.Args()[1:])
+	case cmd.runLinalg != nil:
+		err = cmd.runLinalg(ctx, calculatorpb.NewLinearAlgebraServiceClient(cc), flag.Args()[1:])
+	case cmd.runHistory != nil:
+		err = cmd.runHistory(ctx, calculatorpb.NewHistoryServiceClient(cc), flag.Args()[1:])
+	default:
+		err = cmd.run(ctx, calculatorpb.NewCalculatorServiceClient(cc), flag.Args()[1:])
 	}
-}
-
-func doClientStream(c calculatorpb.CalculatorServiceClient) {
-	nums := []int64{3, 5, 9, 54, 23}
-
-	stream, err := c.ComputeAverage(context.Background())
 	if err != nil {
-		log.Fatalf("Error while calling ComputeAverage %v", err)
+		cancel()
+		cc.Close()
+		log.Fatalf("%s: %v", cmd.name, err)
 	}
+}
 
-	for _, number := range nums {
-		if err := stream.Send(&calculatorpb.ComputeAverageRequest{
-			Number: number,
-		}); err != nil {
-			log.Printf("Failed to send request %v", err)
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
 		}
 	}
-
-	res, err := stream.CloseAndRecv()
-	if err != nil {
-		log.Fatalf("Failed to receive response from ComputeAverage %v", err)
-	}
-	fmt.Printf("Mean is: %v\n", res.GetMean())
+	return command{}, false
 }
 
-func doBiDiStream(c calculatorpb.CalculatorServiceClient) {
-	stream, err := c.FindMaximum(context.Background())
-	if err != nil {
-		log.Fatalf("Failed to call FindMaximum %v", err)
+func usage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintf(w, "Usage: calculator_client [global flags] <command> [args]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %s\n", cmd.usage)
 	}
-
-	waitChan := make(chan struct{})
-
-	go func() {
-		nums := []int64{1, 5, 3, 6, 2, 20}
-		for _, n := range nums {
-			if err := stream.Send(&calculatorpb.FindMaximumRequest{Number: n}); err != nil {
-				log.Fatalf("Failed to send message %v", err)
-			}
-			time.Sleep(1000 * time.Millisecond)
-		}
-		if err := stream.CloseSend(); err != nil {
-			log.Fatalf("Failed to close send stream %v", err)
-		}
-	}()
-
-	go func() {
-		for {
-			res, err := stream.Recv()
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				log.Printf("Failed to receive message %v", err)
-				break
-			}
-			fmt.Printf("Current Max is: %v\n", res.GetMaxNumber())
-		}
-		close(waitChan)
-	}()
-	<-waitChan
+	fmt.Fprintf(w, "\naverage, stats, max and aggregate read numbers from stdin when none are given.\nMatrices are given as \"1,2;3,4\", or as @file with one row per line.\n\nGlobal flags:\n")
+	flag.PrintDefaults()
 }
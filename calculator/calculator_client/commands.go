@@ -0,0 +1,581 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"grpc-go-course/calculator/calculatorpb"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func runSum(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("expected two numbers, got %d", len(args))
+	}
+	a, err := parseInt32(args[0])
+	if err != nil {
+		return err
+	}
+	b, err := parseInt32(args[1])
+	if err != nil {
+		return err
+	}
+
+	res, err := c.Sum(ctx, &calculatorpb.SumRequest{Num_1: a, Num_2: b})
+	if err != nil {
+		return err
+	}
+	fmt.Println(res.GetResult())
+	return nil
+}
+
+func runBigSum(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	res, err := c.BigSum(ctx, &calculatorpb.BigNumbersRequest{Numbers: args})
+	if err != nil {
+		return err
+	}
+	printDecimal(res)
+	return nil
+}
+
+func runBigSubtract(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("expected two numbers, got %d", len(args))
+	}
+	res, err := c.BigSubtract(ctx, &calculatorpb.BigPairRequest{A: args[0], B: args[1]})
+	if err != nil {
+		return err
+	}
+	printDecimal(res)
+	return nil
+}
+
+func runBigMultiply(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	res, err := c.BigMultiply(ctx, &calculatorpb.BigNumbersRequest{Numbers: args})
+	if err != nil {
+		return err
+	}
+	printDecimal(res)
+	return nil
+}
+
+func runBigDivide(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	if len(args) != 2 && len(args) != 3 {
+		return fmt.Errorf("expected two numbers and an optional scale, got %d arguments", len(args))
+	}
+	req := &calculatorpb.BigPairRequest{A: args[0], B: args[1]}
+	if len(args) == 3 {
+		scale, err := parseInt32(args[2])
+		if err != nil {
+			return err
+		}
+		req.Scale = scale
+	}
+	res, err := c.BigDivide(ctx, req)
+	if err != nil {
+		return err
+	}
+	printDecimal(res)
+	return nil
+}
+
+// printDecimal prints the result of a Big RPC, flagging rounded results.
+func printDecimal(res *calculatorpb.BigDecimalResponse) {
+	if res.GetExact() {
+		fmt.Println(res.GetResult())
+		return
+	}
+	fmt.Printf("%s (rounded)\n", res.GetResult())
+}
+
+func runEvaluate(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected an expression")
+	}
+	req := &calculatorpb.EvaluateRequest{Expression: args[0], Variables: map[string]float64{}}
+	for _, arg := range args[1:] {
+		i := strings.IndexByte(arg, '=')
+		if i <= 0 {
+			return fmt.Errorf("expected a variable as name=value, got %q", arg)
+		}
+		v, err := strconv.ParseFloat(arg[i+1:], 64)
+		if err != nil {
+			return err
+		}
+		req.Variables[arg[:i]] = v
+	}
+
+	res, err := c.Evaluate(ctx, req)
+	if err != nil {
+		return err
+	}
+	fmt.Println(res.GetResult())
+	return nil
+}
+
+func runSquareRoot(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected one number, got %d", len(args))
+	}
+	n, err := parseInt32(args[0])
+	if err != nil {
+		return err
+	}
+
+	res, err := c.SquareRoot(ctx, &calculatorpb.SquareRootRequest{Number: n})
+	if err != nil {
+		return err
+	}
+	fmt.Println(res.GetResult())
+	return nil
+}
+
+func runDecompose(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected one number, got %d", len(args))
+	}
+	n, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return err
+	}
+
+	stream, err := c.DecomposePrimeNumber(ctx, &calculatorpb.PrimeNumberDecompositionRequest{Number: n})
+	if err != nil {
+		return err
+	}
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println(msg.GetResult())
+	}
+}
+
+func runAverage(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	stream, err := c.ComputeAverage(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = readNumbers(args, func(n int64) error {
+		return stream.Send(&calculatorpb.ComputeAverageRequest{Number: n})
+	})
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	res, err := stream.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+	fmt.Println(res.GetMean())
+	return nil
+}
+
+func runStatistics(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	stream, err := c.ComputeStatistics(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = readFloats(args, func(n float64) error {
+		return stream.Send(&calculatorpb.ComputeStatisticsRequest{Number: n})
+	})
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	res, err := stream.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("count\t%d\nmean\t%v\nvariance\t%v\nstddev\t%v\nmin\t%v\nmax\t%v\n",
+		res.GetCount(), res.GetMean(), res.GetVariance(), res.GetStddev(), res.GetMin(), res.GetMax())
+	for _, p := range res.GetPercentiles() {
+		fmt.Printf("p%v\t%v\n", p.GetPercentile(), p.GetValue())
+	}
+	return nil
+}
+
+func runMax(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	stream, err := c.FindMaximum(ctx)
+	if err != nil {
+		return err
+	}
+
+	sendErr := make(chan error, 1)
+	go func() {
+		err := readNumbers(args, func(n int64) error {
+			return stream.Send(&calculatorpb.FindMaximumRequest{Number: n})
+		})
+		// A failed Send means the stream is broken; Recv reports why.
+		if err == io.EOF {
+			err = nil
+		}
+		stream.CloseSend()
+		sendErr <- err
+	}()
+
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println(res.GetMaxNumber())
+	}
+	return <-sendErr
+}
+
+// readNumbers calls fn with each number in args, or with each whitespace
+// separated number read from stdin if args is empty. Numbers from stdin are
+// handed over as soon as they are read, so they can be typed interactively.
+func readNumbers(args []string, fn func(int64) error) error {
+	return readWords(args, func(word string) error {
+		n, err := strconv.ParseInt(word, 10, 64)
+		if err != nil {
+			return err
+		}
+		return fn(n)
+	})
+}
+
+// readFloats is readNumbers for floating point numbers.
+func readFloats(args []string, fn func(float64) error) error {
+	return readWords(args, func(word string) error {
+		n, err := strconv.ParseFloat(word, 64)
+		if err != nil {
+			return err
+		}
+		return fn(n)
+	})
+}
+
+// readWords calls fn with each of args, or with each whitespace separated
+// word read from stdin if args is empty.
+func readWords(args []string, fn func(string) error) error {
+	if len(args) > 0 {
+		for _, arg := range args {
+			if err := fn(arg); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Split(bufio.ScanWords)
+	for scanner.Scan() {
+		if err := fn(scanner.Text()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func parseInt32(s string) (int32, error) {
+	n, err := strconv.ParseInt(s, 10, 32)
+	return int32(n), err
+}
+
+var aggregations = map[string]calculatorpb.AggregateConfig_Aggregation{
+	"min":      calculatorpb.AggregateConfig_MIN,
+	"max":      calculatorpb.AggregateConfig_MAX,
+	"sum":      calculatorpb.AggregateConfig_SUM,
+	"mean":     calculatorpb.AggregateConfig_MEAN,
+	"top":      calculatorpb.AggregateConfig_TOP_K,
+	"distinct": calculatorpb.AggregateConfig_DISTINCT_COUNT,
+}
+
+// parseAggregateConfig parses the arguments of the aggregate command: an
+// aggregation such as "max" or "top=3", a window size, and an optional slide
+// starting with "/". Sizes are either counts like "10" or durations like "5s".
+func parseAggregateConfig(args []string) (*calculatorpb.AggregateConfig, []string, error) {
+	if len(args) < 2 {
+		return nil, nil, fmt.Errorf("expected an aggregation and a window size")
+	}
+	cfg := &calculatorpb.AggregateConfig{}
+
+	name := args[0]
+	if i := strings.IndexByte(name, '='); i >= 0 {
+		k, err := parseInt32(name[i+1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		name, cfg.K = name[:i], k
+	}
+	agg, ok := aggregations[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown aggregation %q", args[0])
+	}
+	cfg.Aggregation = agg
+
+	if d, err := time.ParseDuration(args[1]); err == nil {
+		cfg.Size = &calculatorpb.AggregateConfig_SizeDuration{SizeDuration: durationpb.New(d)}
+	} else if n, err := parseInt32(args[1]); err == nil {
+		cfg.Size = &calculatorpb.AggregateConfig_SizeCount{SizeCount: n}
+	} else {
+		return nil, nil, fmt.Errorf("window size %q is neither a count nor a duration", args[1])
+	}
+
+	rest := args[2:]
+	if len(rest) > 0 && strings.HasPrefix(rest[0], "/") {
+		slide := rest[0][1:]
+		if d, err := time.ParseDuration(slide); err == nil {
+			cfg.Slide = &calculatorpb.AggregateConfig_SlideDuration{SlideDuration: durationpb.New(d)}
+		} else if n, err := parseInt32(slide); err == nil {
+			cfg.Slide = &calculatorpb.AggregateConfig_SlideCount{SlideCount: n}
+		} else {
+			return nil, nil, fmt.Errorf("window slide %q is neither a count nor a duration", slide)
+		}
+		rest = rest[1:]
+	}
+	return cfg, rest, nil
+}
+
+func runAggregate(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	cfg, args, err := parseAggregateConfig(args)
+	if err != nil {
+		return err
+	}
+	stream, err := c.StreamAggregate(ctx)
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(&calculatorpb.StreamAggregateRequest{
+		Message: &calculatorpb.StreamAggregateRequest_Config{Config: cfg},
+	}); err != nil && err != io.EOF {
+		return err
+	}
+
+	sendErr := make(chan error, 1)
+	go func() {
+		err := readFloats(args, func(n float64) error {
+			return stream.Send(&calculatorpb.StreamAggregateRequest{
+				Message: &calculatorpb.StreamAggregateRequest_Number{Number: n},
+			})
+		})
+		// A failed Send means the stream is broken; Recv reports why.
+		if err == io.EOF {
+			err = nil
+		}
+		stream.CloseSend()
+		sendErr <- err
+	}()
+
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if res.GetWindowEnd() != nil {
+			fmt.Printf("%s-%s\t", res.GetWindowStart().AsTime().Format("15:04:05.000"), res.GetWindowEnd().AsTime().Format("15:04:05.000"))
+		}
+		if cfg.GetAggregation() == calculatorpb.AggregateConfig_TOP_K {
+			fmt.Printf("%v\t(%d numbers)\n", res.GetTop(), res.GetCount())
+		} else {
+			fmt.Printf("%v\t(%d numbers)\n", res.GetValue(), res.GetCount())
+		}
+	}
+	return <-sendErr
+}
+
+func runIsPrime(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected one number, got %d", len(args))
+	}
+	n, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.IsPrime(ctx, &calculatorpb.IsPrimeRequest{Number: n})
+	if err != nil {
+		return err
+	}
+	fmt.Println(res.GetPrime())
+	return nil
+}
+
+func runGeneratePrimes(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("expected a start and an end, got %d arguments", len(args))
+	}
+	start, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return err
+	}
+	end, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return err
+	}
+
+	stream, err := c.GeneratePrimes(ctx, &calculatorpb.GeneratePrimesRequest{Start: start, End: end})
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, p := range msg.GetPrimes() {
+			fmt.Fprintln(w, p)
+		}
+	}
+}
+
+func runGcd(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	res, err := c.Gcd(ctx, &calculatorpb.BigIntegersRequest{Numbers: args})
+	if err != nil {
+		return err
+	}
+	fmt.Println(res.GetResult())
+	return nil
+}
+
+func runLcm(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	res, err := c.Lcm(ctx, &calculatorpb.BigIntegersRequest{Numbers: args})
+	if err != nil {
+		return err
+	}
+	fmt.Println(res.GetResult())
+	return nil
+}
+
+func runModPow(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("expected a base, an exponent and a modulus, got %d arguments", len(args))
+	}
+	res, err := c.ModPow(ctx, &calculatorpb.ModPowRequest{Base: args[0], Exponent: args[1], Modulus: args[2]})
+	if err != nil {
+		return err
+	}
+	fmt.Println(res.GetResult())
+	return nil
+}
+
+func runModInverse(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("expected a number and a modulus, got %d arguments", len(args))
+	}
+	res, err := c.ModInverse(ctx, &calculatorpb.ModInverseRequest{Number: args[0], Modulus: args[1]})
+	if err != nil {
+		return err
+	}
+	fmt.Println(res.GetResult())
+	return nil
+}
+
+func runFactorial(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected one number, got %d", len(args))
+	}
+	n, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return err
+	}
+	res, err := c.Factorial(ctx, &calculatorpb.FactorialRequest{N: n})
+	if err != nil {
+		return err
+	}
+	fmt.Println(res.GetResult())
+	return nil
+}
+
+func runBinomial(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("expected n and k, got %d arguments", len(args))
+	}
+	n, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return err
+	}
+	k, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return err
+	}
+	res, err := c.Binomial(ctx, &calculatorpb.BinomialRequest{N: n, K: k})
+	if err != nil {
+		return err
+	}
+	fmt.Println(res.GetResult())
+	return nil
+}
+
+func runRoot(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	req := &calculatorpb.RootRequest{}
+	var rest []string
+	for _, arg := range args {
+		if arg == "complex" {
+			req.AllowComplex = true
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	if len(rest) < 1 || len(rest) > 3 {
+		return fmt.Errorf("expected a number, an optional degree and an optional precision")
+	}
+	req.Number = &calculatorpb.RootRequest_Decimal{Decimal: rest[0]}
+	if len(rest) > 1 {
+		degree, err := parseInt32(rest[1])
+		if err != nil {
+			return err
+		}
+		req.Degree = degree
+	}
+	if len(rest) > 2 {
+		precision, err := parseInt32(rest[2])
+		if err != nil {
+			return err
+		}
+		req.Precision = precision
+	}
+
+	res, err := c.Root(ctx, req)
+	if err != nil {
+		return err
+	}
+	if res.GetImaginaryDecimal() != "" {
+		fmt.Printf("%s + %si\n", res.GetDecimal(), res.GetImaginaryDecimal())
+		return nil
+	}
+	fmt.Println(res.GetDecimal())
+	return nil
+}
+
+func runConvert(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("expected a value, a unit to convert from and one to convert to")
+	}
+	value, err := strconv.ParseFloat(args[0], 64)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.Convert(ctx, &calculatorpb.ConvertRequest{Value: value, From: args[1], To: args[2]})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%v %s\n", res.GetValue(), res.GetTo())
+	return nil
+}
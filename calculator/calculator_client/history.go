@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"grpc-go-course/calculator/calculatorpb"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultHistoryLimit = 50
+
+func runHistory(ctx context.Context, c calculatorpb.HistoryServiceClient, args []string) error {
+	req := &calculatorpb.ListHistoryRequest{}
+	limit := defaultHistoryLimit
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("expected key=value, got %q", arg)
+		}
+		var err error
+		switch parts[0] {
+		case "method":
+			req.Method = parts[1]
+		case "start":
+			req.StartTime, err = parseHistoryTime(parts[1])
+		case "end":
+			req.EndTime, err = parseHistoryTime(parts[1])
+		case "limit":
+			limit, err = strconv.Atoi(parts[1])
+			if err == nil && limit < 1 {
+				err = fmt.Errorf("limit must be positive, got %d", limit)
+			}
+		default:
+			return fmt.Errorf("unknown key %q, want method, start, end or limit", parts[0])
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for limit > 0 {
+		req.PageSize = int32(limit)
+		res, err := c.ListHistory(ctx, req)
+		if err != nil {
+			return err
+		}
+		for _, r := range res.GetRecords() {
+			fmt.Printf("%s\t%s/%s\t%s\t%s\t%v\t%s -> %s\n",
+				r.GetTime().AsTime().Local().Format(time.RFC3339Nano), r.GetService(), r.GetMethod(),
+				r.GetCaller(), r.GetCode(), r.GetDuration().AsDuration(),
+				strings.Join(r.GetInputs(), " "), strings.Join(r.GetResults(), " "))
+		}
+		limit -= len(res.GetRecords())
+		if res.GetNextPageToken() == "" {
+			return nil
+		}
+		req.PageToken = res.GetNextPageToken()
+	}
+	return nil
+}
+
+// parseHistoryTime parses an RFC 3339 time, or a duration before now.
+func parseHistoryTime(s string) (*timestamppb.Timestamp, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return timestamppb.New(time.Now().Add(-d)), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, fmt.Errorf("expected an RFC 3339 time or a duration, got %q", s)
+	}
+	return timestamppb.New(t), nil
+}
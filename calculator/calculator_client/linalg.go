@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"grpc-go-course/calculator/calculatorpb"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func runMultiply(ctx context.Context, c calculatorpb.LinearAlgebraServiceClient, args []string) error {
+	return runMatrixOperation(ctx, c, calculatorpb.MatrixOperation_MULTIPLY, args, 2)
+}
+
+func runTranspose(ctx context.Context, c calculatorpb.LinearAlgebraServiceClient, args []string) error {
+	return runMatrixOperation(ctx, c, calculatorpb.MatrixOperation_TRANSPOSE, args, 1)
+}
+
+func runDeterminant(ctx context.Context, c calculatorpb.LinearAlgebraServiceClient, args []string) error {
+	return runMatrixOperation(ctx, c, calculatorpb.MatrixOperation_DETERMINANT, args, 1)
+}
+
+func runInverse(ctx context.Context, c calculatorpb.LinearAlgebraServiceClient, args []string) error {
+	return runMatrixOperation(ctx, c, calculatorpb.MatrixOperation_INVERSE, args, 1)
+}
+
+func runSolve(ctx context.Context, c calculatorpb.LinearAlgebraServiceClient, args []string) error {
+	return runMatrixOperation(ctx, c, calculatorpb.MatrixOperation_SOLVE, args, 2)
+}
+
+// runMatrixOperation calls the unary RPC of op for matrices given inline, and
+// StreamMatrix if any of them is read from a file.
+func runMatrixOperation(ctx context.Context, c calculatorpb.LinearAlgebraServiceClient, op calculatorpb.MatrixOperation, args []string, operands int) error {
+	if len(args) != operands {
+		return fmt.Errorf("expected %d matrices, got %d", operands, len(args))
+	}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "@") {
+			return streamMatrixOperation(ctx, c, op, args)
+		}
+	}
+	matrices := make([]*calculatorpb.Matrix, len(args))
+	for i, arg := range args {
+		m, err := parseMatrix(arg)
+		if err != nil {
+			return fmt.Errorf("matrix %d: %v", i+1, err)
+		}
+		matrices[i] = m
+	}
+
+	var (
+		res *calculatorpb.Matrix
+		err error
+	)
+	switch op {
+	case calculatorpb.MatrixOperation_MULTIPLY:
+		res, err = c.Multiply(ctx, &calculatorpb.MatrixPairRequest{A: matrices[0], B: matrices[1]})
+	case calculatorpb.MatrixOperation_TRANSPOSE:
+		res, err = c.Transpose(ctx, &calculatorpb.MatrixRequest{Matrix: matrices[0]})
+	case calculatorpb.MatrixOperation_DETERMINANT:
+		det, err := c.Determinant(ctx, &calculatorpb.MatrixRequest{Matrix: matrices[0]})
+		if err != nil {
+			return err
+		}
+		fmt.Println(det.GetDeterminant())
+		return nil
+	case calculatorpb.MatrixOperation_INVERSE:
+		res, err = c.Inverse(ctx, &calculatorpb.MatrixRequest{Matrix: matrices[0]})
+	case calculatorpb.MatrixOperation_SOLVE:
+		res, err = c.Solve(ctx, &calculatorpb.MatrixPairRequest{A: matrices[0], B: matrices[1]})
+	}
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(res.GetRows()); i++ {
+		printRow(res.GetValues()[i*int(res.GetCols()) : (i+1)*int(res.GetCols())])
+	}
+	return nil
+}
+
+// streamMatrixOperation calls StreamMatrix. Matrices read from files are
+// scanned once for their dimensions, which go in the header, and once more to
+// send their rows as they are read, so that they are never held in memory.
+func streamMatrixOperation(ctx context.Context, c calculatorpb.LinearAlgebraServiceClient, op calculatorpb.MatrixOperation, args []string) error {
+	sources := make([]matrixSource, len(args))
+	for i, arg := range args {
+		var err error
+		if strings.HasPrefix(arg, "@") {
+			var f *matrixFile
+			if f, err = openMatrixFile(arg[1:]); err == nil {
+				defer f.Close()
+				sources[i] = f
+			}
+		} else {
+			var m *calculatorpb.Matrix
+			if m, err = parseMatrix(arg); err == nil {
+				sources[i] = inlineMatrix{m}
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("matrix %d: %v", i+1, err)
+		}
+	}
+
+	stream, err := c.StreamMatrix(ctx)
+	if err != nil {
+		return err
+	}
+	header := &calculatorpb.StreamMatrixHeader{Operation: op}
+	for _, src := range sources {
+		header.Operands = append(header.Operands, src.dimensions())
+	}
+	err = stream.Send(&calculatorpb.StreamMatrixRequest{
+		Message: &calculatorpb.StreamMatrixRequest_Header{Header: header},
+	})
+	// The server rejects mismatched dimensions without reading the rows, in
+	// which case Send fails with io.EOF and Recv returns the error.
+	for i := 0; i < len(sources) && err == nil; i++ {
+		err = sources[i].eachRow(func(row []float64) error {
+			return stream.Send(&calculatorpb.StreamMatrixRequest{
+				Message: &calculatorpb.StreamMatrixRequest_Row{Row: &calculatorpb.MatrixRow{Values: row}},
+			})
+		})
+		if err != nil && err != io.EOF {
+			err = fmt.Errorf("matrix %d: %v", i+1, err)
+		}
+	}
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch msg := res.GetMessage().(type) {
+		case *calculatorpb.StreamMatrixResponse_Dimensions:
+			fmt.Fprintf(os.Stderr, "%dx%d\n", msg.Dimensions.GetRows(), msg.Dimensions.GetCols())
+		case *calculatorpb.StreamMatrixResponse_Row:
+			printRow(msg.Row.GetValues())
+		case *calculatorpb.StreamMatrixResponse_Determinant:
+			fmt.Println(msg.Determinant)
+		}
+	}
+}
+
+func printRow(values []float64) {
+	words := make([]string, len(values))
+	for i, v := range values {
+		words[i] = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	fmt.Println(strings.Join(words, "\t"))
+}
+
+// matrixSource is an operand of StreamMatrix.
+type matrixSource interface {
+	dimensions() *calculatorpb.MatrixDimensions
+	// eachRow calls fn with each row in turn, until it fails.
+	eachRow(fn func(row []float64) error) error
+}
+
+type inlineMatrix struct {
+	*calculatorpb.Matrix
+}
+
+func (m inlineMatrix) dimensions() *calculatorpb.MatrixDimensions {
+	return &calculatorpb.MatrixDimensions{Rows: m.GetRows(), Cols: m.GetCols()}
+}
+
+func (m inlineMatrix) eachRow(fn func(row []float64) error) error {
+	cols := int(m.GetCols())
+	for i := 0; i < int(m.GetRows()); i++ {
+		if err := fn(m.GetValues()[i*cols : (i+1)*cols]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matrixFile is a matrix read from a file, one row per line of values
+// separated by whitespace or commas. Stdin, which cannot be read twice, is
+// copied to a temporary file while its dimensions are counted.
+type matrixFile struct {
+	f          *os.File
+	temp       bool
+	rows, cols int32
+}
+
+// openMatrixFile opens the matrix in the file name, "-" for stdin, and checks
+// that it is well formed.
+func openMatrixFile(name string) (*matrixFile, error) {
+	m := &matrixFile{}
+	var in io.Reader
+	if name == "-" {
+		f, err := ioutil.TempFile("", "matrix")
+		if err != nil {
+			return nil, err
+		}
+		m.f, m.temp = f, true
+		in = io.TeeReader(os.Stdin, f)
+	} else {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		m.f = f
+		in = f
+	}
+	err := scanRows(in, func(row []float64) error {
+		if m.rows > 0 && len(row) != int(m.cols) {
+			return fmt.Errorf("row %d has %d values, the first has %d", m.rows+1, len(row), m.cols)
+		}
+		m.rows++
+		m.cols = int32(len(row))
+		return nil
+	})
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *matrixFile) dimensions() *calculatorpb.MatrixDimensions {
+	return &calculatorpb.MatrixDimensions{Rows: m.rows, Cols: m.cols}
+}
+
+func (m *matrixFile) eachRow(fn func(row []float64) error) error {
+	if _, err := m.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	rows := int32(0)
+	err := scanRows(m.f, func(row []float64) error {
+		if rows++; rows > m.rows || len(row) != int(m.cols) {
+			return fmt.Errorf("%s changed while it was read", m.f.Name())
+		}
+		return fn(row)
+	})
+	if err == nil && rows != m.rows {
+		err = fmt.Errorf("%s changed while it was read", m.f.Name())
+	}
+	return err
+}
+
+func (m *matrixFile) Close() error {
+	err := m.f.Close()
+	if m.temp {
+		os.Remove(m.f.Name())
+	}
+	return err
+}
+
+// scanRows calls fn with each non-blank line of r parsed as a row.
+func scanRows(r io.Reader, fn func(row []float64) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		row, err := parseRow(line)
+		if err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// parseMatrix parses a matrix given inline as rows separated by semicolons of
+// values separated by commas, such as "1,2;3,4".
+func parseMatrix(arg string) (*calculatorpb.Matrix, error) {
+	rows := strings.Split(arg, ";")
+	m := &calculatorpb.Matrix{Rows: int32(len(rows))}
+	for i, line := range rows {
+		row, err := parseRow(line)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			m.Cols = int32(len(row))
+		} else if len(row) != int(m.Cols) {
+			return nil, fmt.Errorf("row %d has %d values, the first has %d", i+1, len(row), m.Cols)
+		}
+		m.Values = append(m.Values, row...)
+	}
+	return m, nil
+}
+
+// parseRow parses values separated by commas or whitespace.
+func parseRow(line string) ([]float64, error) {
+	values := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+	row := make([]float64, len(values))
+	for i, v := range values {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, err
+		}
+		row[i] = f
+	}
+	return row, nil
+}
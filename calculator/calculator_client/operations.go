@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/genproto/googleapis/longrunning"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"grpc-go-course/calculator/calculatorpb"
+	"time"
+)
+
+func runFactorize(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected one number, got %d", len(args))
+	}
+	op, err := c.StartFactorization(ctx, &calculatorpb.StartFactorizationRequest{Number: args[0]})
+	if err != nil {
+		return err
+	}
+	fmt.Println(op.GetName())
+	return nil
+}
+
+func runOperation(ctx context.Context, c longrunning.OperationsClient, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected an operation name")
+	}
+	op, err := c.GetOperation(ctx, &longrunning.GetOperationRequest{Name: args[0]})
+	if err != nil {
+		return err
+	}
+	return printMessage(op)
+}
+
+func runListOperations(ctx context.Context, c longrunning.OperationsClient, args []string) error {
+	req := &longrunning.ListOperationsRequest{}
+	if len(args) > 0 {
+		req.Filter = args[0]
+	}
+	for {
+		res, err := c.ListOperations(ctx, req)
+		if err != nil {
+			return err
+		}
+		for _, op := range res.GetOperations() {
+			state := "running"
+			switch {
+			case op.GetError() != nil:
+				state = "failed: " + op.GetError().GetMessage()
+			case op.GetDone():
+				state = "done"
+			}
+			fmt.Printf("%s\t%s\n", op.GetName(), state)
+		}
+		if res.GetNextPageToken() == "" {
+			return nil
+		}
+		req.PageToken = res.GetNextPageToken()
+	}
+}
+
+func runWaitOperation(ctx context.Context, c longrunning.OperationsClient, args []string) error {
+	if len(args) != 1 && len(args) != 2 {
+		return fmt.Errorf("expected an operation name and an optional timeout")
+	}
+	req := &longrunning.WaitOperationRequest{Name: args[0]}
+	if len(args) == 2 {
+		d, err := time.ParseDuration(args[1])
+		if err != nil {
+			return err
+		}
+		req.Timeout = durationpb.New(d)
+	}
+	op, err := c.WaitOperation(ctx, req)
+	if err != nil {
+		return err
+	}
+	return printMessage(op)
+}
+
+func runCancelOperation(ctx context.Context, c longrunning.OperationsClient, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected an operation name")
+	}
+	_, err := c.CancelOperation(ctx, &longrunning.CancelOperationRequest{Name: args[0]})
+	return err
+}
+
+func runDeleteOperation(ctx context.Context, c longrunning.OperationsClient, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected an operation name")
+	}
+	_, err := c.DeleteOperation(ctx, &longrunning.DeleteOperationRequest{Name: args[0]})
+	return err
+}
+
+func printMessage(m proto.Message) error {
+	b, err := protojson.MarshalOptions{Multiline: true}.Marshal(m)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"grpc-go-course/calculator/calculatorpb"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sessionOps are the words of the session command naming operations.
+var sessionOps = map[string]calculatorpb.SessionRequest_Operation{
+	"+":     calculatorpb.SessionRequest_ADD,
+	"-":     calculatorpb.SessionRequest_SUBTRACT,
+	"*":     calculatorpb.SessionRequest_MULTIPLY,
+	"/":     calculatorpb.SessionRequest_DIVIDE,
+	"^":     calculatorpb.SessionRequest_POWER,
+	"neg":   calculatorpb.SessionRequest_NEGATE,
+	"sqrt":  calculatorpb.SessionRequest_SQRT,
+	"dup":   calculatorpb.SessionRequest_DUP,
+	"swap":  calculatorpb.SessionRequest_SWAP,
+	"clear": calculatorpb.SessionRequest_CLEAR,
+}
+
+// runSession reads commands from stdin, a line at a time, and prints the
+// state of the session after each line. A line holds numbers to push,
+// operations, "pop", "sto <register>" and "rcl <register>", such as "3 4 +".
+func runSession(ctx context.Context, c calculatorpb.CalculatorServiceClient, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("expected at most a session ID, got %d arguments", len(args))
+	}
+	stream, err := c.Session(ctx)
+	if err != nil {
+		return err
+	}
+	start := &calculatorpb.SessionRequest_Start{}
+	if len(args) == 1 {
+		start.SessionId = args[0]
+	}
+	res, err := sessionCommand(stream, &calculatorpb.SessionRequest{
+		Command: &calculatorpb.SessionRequest_Start_{Start: start},
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("session %s\n", res.GetSessionId())
+	printSession(res)
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		words := strings.Fields(scanner.Text())
+		for i := 0; i < len(words); i++ {
+			req, err := parseSessionCommand(words[i:])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				break
+			}
+			word := words[i]
+			if req.GetStore() != "" || req.GetRecall() != "" {
+				i++
+			}
+			if res, err = sessionCommand(stream, req); err != nil {
+				return err
+			}
+			if res.GetError() != "" {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", word, res.GetError())
+				break
+			}
+		}
+		printSession(res)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return stream.CloseSend()
+}
+
+// parseSessionCommand parses the command at the start of words.
+func parseSessionCommand(words []string) (*calculatorpb.SessionRequest, error) {
+	req := &calculatorpb.SessionRequest{}
+	switch w := words[0]; w {
+	case "pop":
+		req.Command = &calculatorpb.SessionRequest_Pop_{Pop: &calculatorpb.SessionRequest_Pop{}}
+	case "sto", "rcl":
+		if len(words) < 2 {
+			return nil, fmt.Errorf("%s needs a register name", w)
+		}
+		if w == "sto" {
+			req.Command = &calculatorpb.SessionRequest_Store{Store: words[1]}
+		} else {
+			req.Command = &calculatorpb.SessionRequest_Recall{Recall: words[1]}
+		}
+	default:
+		if op, ok := sessionOps[w]; ok {
+			req.Command = &calculatorpb.SessionRequest_Operation_{Operation: op}
+			break
+		}
+		v, err := strconv.ParseFloat(w, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unknown command %q", w)
+		}
+		req.Command = &calculatorpb.SessionRequest_Push{Push: v}
+	}
+	return req, nil
+}
+
+func sessionCommand(stream calculatorpb.CalculatorService_SessionClient, req *calculatorpb.SessionRequest) (*calculatorpb.SessionResponse, error) {
+	if err := stream.Send(req); err != nil {
+		// The server ended the stream, Recv returns why.
+		_, err = stream.Recv()
+		return nil, err
+	}
+	return stream.Recv()
+}
+
+func printSession(res *calculatorpb.SessionResponse) {
+	var stack []string
+	for _, v := range res.GetStack() {
+		stack = append(stack, strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	fmt.Printf("[%s]", strings.Join(stack, " "))
+	names := make([]string, 0, len(res.GetRegisters()))
+	for name := range res.GetRegisters() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf(" %s=%v", name, res.GetRegisters()[name])
+	}
+	fmt.Println()
+}
@@ -0,0 +1,66 @@
+// Command calculator_gateway serves the unary and server streaming RPCs of
+// the CalculatorService, LinearAlgebraService and HistoryService as a REST/JSON
+// API, proxying every request to a calculator server over gRPC:
+//
+//	POST /v1/calculator/sum              Sum, the body is {"num1": a, "num2": b}
+//	GET  /v1/calculator/sqrt/{number}    SquareRoot
+//	GET  /v1/calculator/factors/{number} DecomposePrimeNumber, streamed as
+//	                                     newline delimited {"result": ...}
+//	POST /v1/linalg/{operation}          Multiply, Transpose, Determinant,
+//	                                     Inverse and Solve of the
+//	                                     LinearAlgebraService
+//	GET  /v1/history                     ListHistory of the HistoryService
+//	GET  /openapi.json                   the OpenAPI document of the API
+package main
+
+import (
+	"context"
+	"flag"
+	"grpc-go-course/calculator/calculatorpb"
+	"grpc-go-course/dial"
+	"grpc-go-course/gateway"
+	"log"
+)
+
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	address := flag.String("http-addr", "0.0.0.0:8081", "address to serve HTTP on")
+	specFile := flag.String("openapi", "", "OpenAPI document to serve at "+gateway.SpecPath+" (default: the one built into the binary)")
+	var tlsFlags gateway.TLSFlags
+	tlsFlags.Register(flag.CommandLine)
+	var df dial.Flags
+	df.Register(flag.CommandLine, dial.Defaults{
+		Addr:      "localhost:50051",
+		CAFile:    "ssl/ca.crt",
+		Plaintext: true,
+	})
+	flag.Parse()
+	tlsCfg, err := tlsFlags.Config()
+	if err != nil {
+		log.Fatalf("Failed to load the HTTPS certificate: %v", err)
+	}
+
+	cc, err := df.Dial(context.Background())
+	if err != nil {
+		log.Fatalf("Could not connect to the calculator server: %v", err)
+	}
+	defer cc.Close()
+
+	mux := gateway.NewMux()
+	if err := calculatorpb.RegisterCalculatorServiceHandler(context.Background(), mux, cc); err != nil {
+		log.Fatalf("Failed to register the CalculatorService handler: %v", err)
+	}
+	if err := calculatorpb.RegisterLinearAlgebraServiceHandler(context.Background(), mux, cc); err != nil {
+		log.Fatalf("Failed to register the LinearAlgebraService handler: %v", err)
+	}
+	if err := calculatorpb.RegisterHistoryServiceHandler(context.Background(), mux, cc); err != nil {
+		log.Fatalf("Failed to register the HistoryService handler: %v", err)
+	}
+
+	spec, err := gateway.LoadSpec(*specFile, calculatorpb.OpenAPI)
+	if err != nil {
+		log.Fatalf("Failed to load the OpenAPI document: %v", err)
+	}
+
+	gateway.ListenAndServe(*address, gateway.WithSpec(mux, spec), tlsCfg)
+}
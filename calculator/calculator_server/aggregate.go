@@ -0,0 +1,207 @@
+package main
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"grpc-go-course/calculator/aggregate"
+	"grpc-go-course/calculator/calculatorpb"
+	"io"
+	"math"
+	"time"
+)
+
+const (
+	// maxWindowCount bounds the size of count windows.
+	maxWindowCount = 100000
+	// maxWindowDuration bounds the size of time windows, minWindowSlide how
+	// often they can close.
+	maxWindowDuration = time.Hour
+	minWindowSlide    = 10 * time.Millisecond
+	// maxWindowNumbers bounds how many numbers a time window can hold.
+	maxWindowNumbers = 100000
+	// maxTopK bounds the values kept by TOP_K.
+	maxTopK = 1000
+)
+
+var aggregationKinds = map[calculatorpb.AggregateConfig_Aggregation]aggregate.Kind{
+	calculatorpb.AggregateConfig_MIN:            aggregate.Min,
+	calculatorpb.AggregateConfig_MAX:            aggregate.Max,
+	calculatorpb.AggregateConfig_SUM:            aggregate.Sum,
+	calculatorpb.AggregateConfig_MEAN:           aggregate.Mean,
+	calculatorpb.AggregateConfig_TOP_K:          aggregate.TopK,
+	calculatorpb.AggregateConfig_DISTINCT_COUNT: aggregate.DistinctCount,
+}
+
+func (s *server) StreamAggregate(stream calculatorpb.CalculatorService_StreamAggregateServer) error {
+	req, err := stream.Recv()
+	if err == io.EOF {
+		return status.Errorf(codes.InvalidArgument, "Received no configuration")
+	}
+	if err != nil {
+		return err
+	}
+	cfg := req.GetConfig()
+	if cfg == nil {
+		return status.Errorf(codes.InvalidArgument, "The first message must configure the aggregation")
+	}
+
+	kind, ok := aggregationKinds[cfg.GetAggregation()]
+	if !ok {
+		return status.Errorf(codes.InvalidArgument, "Unknown aggregation %v", cfg.GetAggregation())
+	}
+	k := int(cfg.GetK())
+	if kind == aggregate.TopK && (k < 1 || k > maxTopK) {
+		return status.Errorf(codes.InvalidArgument, "K must be between 1 and %d, got %d", maxTopK, k)
+	}
+
+	switch size := cfg.GetSize().(type) {
+	case *calculatorpb.AggregateConfig_SizeCount:
+		return aggregateByCount(stream, cfg, kind, k)
+	case *calculatorpb.AggregateConfig_SizeDuration:
+		return aggregateByTime(stream, cfg, kind, k)
+	default:
+		return status.Errorf(codes.InvalidArgument, "Window size is required, got %T", size)
+	}
+}
+
+func aggregateByCount(stream calculatorpb.CalculatorService_StreamAggregateServer, cfg *calculatorpb.AggregateConfig, kind aggregate.Kind, k int) error {
+	size := int(cfg.GetSizeCount())
+	if size < 1 || size > maxWindowCount {
+		return status.Errorf(codes.InvalidArgument, "Window size must be between 1 and %d numbers, got %d", maxWindowCount, size)
+	}
+	slide := size
+	switch cfg.GetSlide().(type) {
+	case nil:
+	case *calculatorpb.AggregateConfig_SlideCount:
+		slide = int(cfg.GetSlideCount())
+	default:
+		return status.Errorf(codes.InvalidArgument, "A window sized by count must slide by count")
+	}
+	if slide < 1 || slide > size {
+		return status.Errorf(codes.InvalidArgument, "Window slide must be between 1 and %d numbers, got %d", size, slide)
+	}
+
+	w := aggregate.NewCountWindow(size, slide)
+	for {
+		n, err := recvNumber(stream)
+		if err == io.EOF {
+			if values, ok := w.Flush(); ok {
+				return stream.Send(aggregateResponse(aggregate.Compute(kind, k, values)))
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if values, ok := w.Add(n); ok {
+			if err := stream.Send(aggregateResponse(aggregate.Compute(kind, k, values))); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func aggregateByTime(stream calculatorpb.CalculatorService_StreamAggregateServer, cfg *calculatorpb.AggregateConfig, kind aggregate.Kind, k int) error {
+	size := cfg.GetSizeDuration().AsDuration()
+	if size < minWindowSlide || size > maxWindowDuration {
+		return status.Errorf(codes.InvalidArgument, "Window size must be between %v and %v, got %v", minWindowSlide, maxWindowDuration, size)
+	}
+	slide := size
+	switch cfg.GetSlide().(type) {
+	case nil:
+	case *calculatorpb.AggregateConfig_SlideDuration:
+		slide = cfg.GetSlideDuration().AsDuration()
+	default:
+		return status.Errorf(codes.InvalidArgument, "A window sized by time must slide by time")
+	}
+	if slide < minWindowSlide || slide > size {
+		return status.Errorf(codes.InvalidArgument, "Window slide must be between %v and %v, got %v", minWindowSlide, size, slide)
+	}
+
+	// Receive in the background, so that windows close on time while the
+	// client is idle.
+	ctx := stream.Context()
+	numbers := make(chan float64)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			n, err := recvNumber(stream)
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case numbers <- n:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	w := aggregate.NewTimeWindow(time.Now(), size, slide)
+	timer := time.NewTimer(time.Until(w.End()))
+	defer timer.Stop()
+	for {
+		select {
+		case n := <-numbers:
+			if w.Len() >= maxWindowNumbers {
+				return status.Errorf(codes.ResourceExhausted, "A window can hold at most %d numbers", maxWindowNumbers)
+			}
+			w.Add(time.Now(), n)
+
+		case <-timer.C:
+			start, end, values := w.Close()
+			timer.Reset(time.Until(w.End()))
+			if len(values) == 0 {
+				continue
+			}
+			if err := stream.Send(timeWindowResponse(aggregate.Compute(kind, k, values), start, end)); err != nil {
+				return err
+			}
+
+		case err := <-recvErr:
+			if err != io.EOF {
+				return err
+			}
+			if start, end, values, ok := w.Flush(time.Now()); ok && len(values) > 0 {
+				return stream.Send(timeWindowResponse(aggregate.Compute(kind, k, values), start, end))
+			}
+			return nil
+
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+// recvNumber receives the next number of a StreamAggregate call.
+func recvNumber(stream calculatorpb.CalculatorService_StreamAggregateServer) (float64, error) {
+	req, err := stream.Recv()
+	if err != nil {
+		return 0, err
+	}
+	if req.GetConfig() != nil {
+		return 0, status.Errorf(codes.InvalidArgument, "The aggregation can only be configured by the first message")
+	}
+	n := req.GetNumber()
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, status.Errorf(codes.InvalidArgument, "Number is not finite: %v", n)
+	}
+	return n, nil
+}
+
+func aggregateResponse(res aggregate.Result) *calculatorpb.StreamAggregateResponse {
+	return &calculatorpb.StreamAggregateResponse{
+		Value: res.Value,
+		Top:   res.Top,
+		Count: int64(res.Count),
+	}
+}
+
+func timeWindowResponse(res aggregate.Result, start, end time.Time) *calculatorpb.StreamAggregateResponse {
+	msg := aggregateResponse(res)
+	msg.WindowStart = timestamppb.New(start)
+	msg.WindowEnd = timestamppb.New(end)
+	return msg
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"grpc-go-course/calculator/calculatorpb"
+	"grpc-go-course/calculator/decimal"
+	"math/big"
+)
+
+// defaultScale is the number of digits BigDivide rounds a quotient with no
+// finite decimal expansion to, unless the request asks for another.
+const defaultScale = 20
+
+func (s *server) BigSum(ctx context.Context, req *calculatorpb.BigNumbersRequest) (*calculatorpb.BigDecimalResponse, error) {
+	nums, err := parseDecimals(req.GetNumbers())
+	if err != nil {
+		return nil, err
+	}
+	sum := new(big.Rat)
+	for _, n := range nums {
+		sum.Add(sum, n)
+	}
+	return decimalResponse(sum, decimal.MaxScale), nil
+}
+
+func (s *server) BigSubtract(ctx context.Context, req *calculatorpb.BigPairRequest) (*calculatorpb.BigDecimalResponse, error) {
+	nums, err := parseDecimals([]string{req.GetA(), req.GetB()})
+	if err != nil {
+		return nil, err
+	}
+	return decimalResponse(new(big.Rat).Sub(nums[0], nums[1]), decimal.MaxScale), nil
+}
+
+func (s *server) BigMultiply(ctx context.Context, req *calculatorpb.BigNumbersRequest) (*calculatorpb.BigDecimalResponse, error) {
+	nums, err := parseDecimals(req.GetNumbers())
+	if err != nil {
+		return nil, err
+	}
+	product := big.NewRat(1, 1)
+	for _, n := range nums {
+		product.Mul(product, n)
+	}
+	return decimalResponse(product, decimal.MaxScale), nil
+}
+
+func (s *server) BigDivide(ctx context.Context, req *calculatorpb.BigPairRequest) (*calculatorpb.BigDecimalResponse, error) {
+	scale := int(req.GetScale())
+	if scale < 0 || scale > decimal.MaxScale {
+		return nil, status.Errorf(codes.InvalidArgument, "Scale must be between 0 and %d, got %d", decimal.MaxScale, scale)
+	}
+	if scale == 0 {
+		scale = defaultScale
+	}
+	nums, err := parseDecimals([]string{req.GetA(), req.GetB()})
+	if err != nil {
+		return nil, err
+	}
+	if nums[1].Sign() == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Division by zero")
+	}
+	return decimalResponse(new(big.Rat).Quo(nums[0], nums[1]), scale), nil
+}
+
+// parseDecimals parses the operands of a Big RPC, failing with
+// INVALID_ARGUMENT on the first one that isn't a decimal number.
+func parseDecimals(ss []string) ([]*big.Rat, error) {
+	if len(ss) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "No numbers given")
+	}
+	nums := make([]*big.Rat, len(ss))
+	for i, s := range ss {
+		n, err := decimal.Parse(s)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Number %d: %v", i+1, err)
+		}
+		nums[i] = n
+	}
+	return nums, nil
+}
+
+func decimalResponse(r *big.Rat, scale int) *calculatorpb.BigDecimalResponse {
+	result, exact := decimal.Format(r, scale)
+	return &calculatorpb.BigDecimalResponse{Result: result, Exact: exact}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"grpc-go-course/calculator/calculatorpb"
+	"math"
+)
+
+func (s *server) Convert(ctx context.Context, req *calculatorpb.ConvertRequest) (*calculatorpb.ConvertResponse, error) {
+	from, err := s.units.Lookup(req.GetFrom())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "From: %v", err)
+	}
+	to, err := s.units.Lookup(req.GetTo())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "To: %v", err)
+	}
+	value, err := s.units.Convert(req.GetValue(), from.Name, to.Name)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Cannot convert %v %s to %s: %v", req.GetValue(), from.Name, to.Name, err)
+	}
+	if math.IsInf(value, 0) {
+		return nil, status.Errorf(codes.OutOfRange, "%v %s in %s overflows a double", req.GetValue(), from.Name, to.Name)
+	}
+	return &calculatorpb.ConvertResponse{
+		Value:     value,
+		Dimension: from.Dimension,
+		From:      from.Name,
+		To:        to.Name,
+	}, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"grpc-go-course/calculator/calculatorpb"
+	"grpc-go-course/calculator/expr"
+	"math"
+)
+
+func (s *server) Evaluate(ctx context.Context, req *calculatorpb.EvaluateRequest) (*calculatorpb.EvaluateResponse, error) {
+	e, err := expr.Parse(req.GetExpression())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid expression at %v", err)
+	}
+	result, err := e.Eval(req.GetVariables())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Cannot evaluate expression at %v", err)
+	}
+	if math.IsInf(result, 0) {
+		return nil, status.Errorf(codes.OutOfRange, "Result of %q overflows a double", req.GetExpression())
+	}
+	return &calculatorpb.EvaluateResponse{Result: result}, nil
+}
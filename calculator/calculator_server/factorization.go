@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"google.golang.org/genproto/googleapis/longrunning"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"grpc-go-course/calculator/calculatorpb"
+	"grpc-go-course/calculator/primes"
+	"grpc-go-course/operations"
+	"math/big"
+	"regexp"
+	"sort"
+)
+
+// maxFactorizationDigits bounds the numbers StartFactorization accepts.
+const maxFactorizationDigits = 200
+
+var positiveInteger = regexp.MustCompile(`^[0-9]+$`)
+
+func (s *server) StartFactorization(ctx context.Context, req *calculatorpb.StartFactorizationRequest) (*longrunning.Operation, error) {
+	num := req.GetNumber()
+	if len(num) > maxFactorizationDigits {
+		return nil, status.Errorf(codes.InvalidArgument, "Number has more than %d digits", maxFactorizationDigits)
+	}
+	n, ok := new(big.Int).SetString(num, 10)
+	if !positiveInteger.MatchString(num) || !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "Number %q is not a positive integer", num)
+	}
+	if n.Sign() <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Received a non-positive number %v", n)
+	}
+
+	md := &calculatorpb.FactorizationMetadata{Number: n.String(), StartTime: timestamppb.Now()}
+	return s.ops.Start(md, func(ctx context.Context, progress operations.Progress) (proto.Message, error) {
+		var factors []*big.Int
+		err := primes.FactorBig(ctx, n, func(p *big.Int) error {
+			factors = append(factors, p)
+			md.Factors = append(md.Factors, p.String())
+			progress(md)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		md.EndTime = timestamppb.Now()
+		progress(md)
+
+		sort.Slice(factors, func(i, j int) bool { return factors[i].Cmp(factors[j]) < 0 })
+		res := &calculatorpb.FactorizationResponse{Number: n.String()}
+		for _, p := range factors {
+			res.Factors = append(res.Factors, p.String())
+		}
+		return res, nil
+	})
+}
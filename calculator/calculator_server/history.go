@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"grpc-go-course/calculator/calculatorpb"
+	"grpc-go-course/history"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultHistoryPageSize = 50
+	maxHistoryPageSize     = 1000
+)
+
+type historyServer struct {
+	store history.Store
+}
+
+func (s *historyServer) ListHistory(ctx context.Context, req *calculatorpb.ListHistoryRequest) (*calculatorpb.ListHistoryResponse, error) {
+	var f history.Filter
+	if method := req.GetMethod(); method != "" {
+		if i := strings.LastIndex(method, "/"); i >= 0 {
+			f.Service, f.Method = strings.TrimPrefix(method[:i], "/"), method[i+1:]
+		} else {
+			f.Method = method
+		}
+	}
+	if t := req.GetStartTime(); t != nil {
+		if err := t.CheckValid(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid start time %v", err)
+		}
+		f.Start = t.AsTime()
+	}
+	if t := req.GetEndTime(); t != nil {
+		if err := t.CheckValid(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid end time %v", err)
+		}
+		f.End = t.AsTime()
+	}
+	if !f.Start.IsZero() && !f.End.IsZero() && !f.Start.Before(f.End) {
+		return nil, status.Errorf(codes.InvalidArgument, "Start time %v is not before end time %v", f.Start, f.End)
+	}
+
+	size := int(req.GetPageSize())
+	switch {
+	case size < 0:
+		return nil, status.Errorf(codes.InvalidArgument, "Negative page size %d", size)
+	case size == 0:
+		size = defaultHistoryPageSize
+	case size > maxHistoryPageSize:
+		size = maxHistoryPageSize
+	}
+	f.Limit = size + 1
+
+	if token := req.GetPageToken(); token != "" {
+		id, err := decodeHistoryPageToken(token)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid page token %q", token)
+		}
+		f.After = id
+	}
+
+	rs, err := s.store.List(f)
+	if errors.Is(err, history.ErrInvalidCursor) {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid page token %q", req.GetPageToken())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Cannot list history %v", err)
+	}
+	res := &calculatorpb.ListHistoryResponse{}
+	if len(rs) > size {
+		rs = rs[:size]
+		res.NextPageToken = encodeHistoryPageToken(rs[size-1].ID)
+	}
+	for _, r := range rs {
+		res.Records = append(res.Records, &calculatorpb.HistoryRecord{
+			Id:        r.ID,
+			Time:      timestamppb.New(r.Time),
+			Duration:  durationpb.New(r.Duration),
+			Caller:    r.Caller,
+			Service:   r.Service,
+			Method:    r.Method,
+			Inputs:    r.Inputs,
+			Results:   r.Results,
+			Truncated: r.Truncated,
+			Code:      r.Code,
+			Error:     r.Error,
+		})
+	}
+	return res, nil
+}
+
+// History page tokens encode the ID of the last record of the previous page.
+func encodeHistoryPageToken(id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(id))
+}
+
+func decodeHistoryPageToken(token string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", err
+	}
+	if len(b) == 0 {
+		return "", strconv.ErrSyntax
+	}
+	return string(b), nil
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"grpc-go-course/calculator/calculatorpb"
+	"grpc-go-course/calculator/linalg"
+	"io"
+	"math"
+)
+
+type linalgServer struct{}
+
+func (s *linalgServer) Multiply(ctx context.Context, req *calculatorpb.MatrixPairRequest) (*calculatorpb.Matrix, error) {
+	a, b, err := matrixPair(req)
+	if err != nil {
+		return nil, err
+	}
+	c, err := linalg.Mul(ctx, a, b)
+	if err != nil {
+		return nil, linalgError(ctx, err)
+	}
+	if err := checkFinite(c); err != nil {
+		return nil, err
+	}
+	return matrixToProto(c), nil
+}
+
+func (s *linalgServer) Transpose(ctx context.Context, req *calculatorpb.MatrixRequest) (*calculatorpb.Matrix, error) {
+	m, err := matrixFromProto("matrix", req.GetMatrix())
+	if err != nil {
+		return nil, err
+	}
+	return matrixToProto(linalg.Transpose(m)), nil
+}
+
+func (s *linalgServer) Determinant(ctx context.Context, req *calculatorpb.MatrixRequest) (*calculatorpb.DeterminantResponse, error) {
+	m, err := matrixFromProto("matrix", req.GetMatrix())
+	if err != nil {
+		return nil, err
+	}
+	det, err := linalg.Det(ctx, m)
+	if err != nil {
+		return nil, linalgError(ctx, err)
+	}
+	if math.IsInf(det, 0) {
+		return nil, status.Errorf(codes.OutOfRange, "Determinant overflows a double")
+	}
+	return &calculatorpb.DeterminantResponse{Determinant: det}, nil
+}
+
+func (s *linalgServer) Inverse(ctx context.Context, req *calculatorpb.MatrixRequest) (*calculatorpb.Matrix, error) {
+	m, err := matrixFromProto("matrix", req.GetMatrix())
+	if err != nil {
+		return nil, err
+	}
+	inv, err := linalg.Inverse(ctx, m)
+	if err != nil {
+		return nil, linalgError(ctx, err)
+	}
+	if err := checkFinite(inv); err != nil {
+		return nil, err
+	}
+	return matrixToProto(inv), nil
+}
+
+func (s *linalgServer) Solve(ctx context.Context, req *calculatorpb.MatrixPairRequest) (*calculatorpb.Matrix, error) {
+	a, b, err := matrixPair(req)
+	if err != nil {
+		return nil, err
+	}
+	x, err := linalg.Solve(ctx, a, b)
+	if err != nil {
+		return nil, linalgError(ctx, err)
+	}
+	if err := checkFinite(x); err != nil {
+		return nil, err
+	}
+	return matrixToProto(x), nil
+}
+
+// operandCounts is how many matrices each operation of StreamMatrix takes.
+var operandCounts = map[calculatorpb.MatrixOperation]int{
+	calculatorpb.MatrixOperation_MULTIPLY:    2,
+	calculatorpb.MatrixOperation_TRANSPOSE:   1,
+	calculatorpb.MatrixOperation_DETERMINANT: 1,
+	calculatorpb.MatrixOperation_INVERSE:     1,
+	calculatorpb.MatrixOperation_SOLVE:       2,
+}
+
+// operandNames names the operands in errors.
+var operandNames = []string{"A", "B"}
+
+func (s *linalgServer) StreamMatrix(stream calculatorpb.LinearAlgebraService_StreamMatrixServer) error {
+	ctx := stream.Context()
+	req, err := stream.Recv()
+	if err == io.EOF {
+		return status.Errorf(codes.InvalidArgument, "Received no header")
+	}
+	if err != nil {
+		return err
+	}
+	header := req.GetHeader()
+	if header == nil {
+		return status.Errorf(codes.InvalidArgument, "The first message must be the header")
+	}
+	op := header.GetOperation()
+	count, ok := operandCounts[op]
+	if !ok {
+		return status.Errorf(codes.InvalidArgument, "Unknown operation %v", op)
+	}
+	if len(header.GetOperands()) != count {
+		return status.Errorf(codes.InvalidArgument, "%v takes %d operands, got %d", op, count, len(header.GetOperands()))
+	}
+
+	// The operands are checked before reading any row, so that mismatched
+	// dimensions fail without sending the matrices. Their storage grows as
+	// the rows arrive rather than as the header announces, which costs
+	// nothing to send.
+	operands := make([]*linalg.Matrix, count)
+	for i, dims := range header.GetOperands() {
+		rows, cols := int(dims.GetRows()), int(dims.GetCols())
+		if err := linalg.CheckDimensions(rows, cols); err != nil {
+			return status.Errorf(codes.InvalidArgument, "Matrix %s: %v", operandNames[i], err)
+		}
+		operands[i] = &linalg.Matrix{Rows: rows, Cols: cols}
+	}
+	switch op {
+	case calculatorpb.MatrixOperation_MULTIPLY:
+		err = linalg.CheckMul(operands[0], operands[1])
+	case calculatorpb.MatrixOperation_DETERMINANT, calculatorpb.MatrixOperation_INVERSE:
+		err = linalg.CheckSquare(operands[0])
+	case calculatorpb.MatrixOperation_SOLVE:
+		err = linalg.CheckSolve(operands[0], operands[1])
+	}
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	for i, m := range operands {
+		for r := 0; r < m.Rows; r++ {
+			req, err := stream.Recv()
+			if err == io.EOF {
+				return status.Errorf(codes.InvalidArgument, "Matrix %s has %d rows, received %d", operandNames[i], m.Rows, r)
+			}
+			if err != nil {
+				return err
+			}
+			row := req.GetRow()
+			if row == nil {
+				return status.Errorf(codes.InvalidArgument, "Expected row %d of matrix %s, got a header", r+1, operandNames[i])
+			}
+			if len(row.GetValues()) != m.Cols {
+				return status.Errorf(codes.InvalidArgument, "Row %d of matrix %s has %d values, expected %d", r+1, operandNames[i], len(row.GetValues()), m.Cols)
+			}
+			m.Data = append(m.Data, row.GetValues()...)
+		}
+		if _, err := linalg.New(m.Rows, m.Cols, m.Data); err != nil {
+			return status.Errorf(codes.InvalidArgument, "Matrix %s: %v", operandNames[i], err)
+		}
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		if err != nil {
+			return err
+		}
+		return status.Errorf(codes.InvalidArgument, "Received more rows than the header announced")
+	}
+
+	var res *linalg.Matrix
+	switch op {
+	case calculatorpb.MatrixOperation_MULTIPLY:
+		res, err = linalg.Mul(ctx, operands[0], operands[1])
+	case calculatorpb.MatrixOperation_TRANSPOSE:
+		res = linalg.Transpose(operands[0])
+	case calculatorpb.MatrixOperation_DETERMINANT:
+		var det float64
+		if det, err = linalg.Det(ctx, operands[0]); err == nil {
+			if math.IsInf(det, 0) {
+				return status.Errorf(codes.OutOfRange, "Determinant overflows a double")
+			}
+			return stream.Send(&calculatorpb.StreamMatrixResponse{
+				Message: &calculatorpb.StreamMatrixResponse_Determinant{Determinant: det},
+			})
+		}
+	case calculatorpb.MatrixOperation_INVERSE:
+		res, err = linalg.Inverse(ctx, operands[0])
+	case calculatorpb.MatrixOperation_SOLVE:
+		res, err = linalg.Solve(ctx, operands[0], operands[1])
+	}
+	if err != nil {
+		return linalgError(ctx, err)
+	}
+	if err := checkFinite(res); err != nil {
+		return err
+	}
+
+	err = stream.Send(&calculatorpb.StreamMatrixResponse{
+		Message: &calculatorpb.StreamMatrixResponse_Dimensions{
+			Dimensions: &calculatorpb.MatrixDimensions{Rows: int32(res.Rows), Cols: int32(res.Cols)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	for i := 0; i < res.Rows; i++ {
+		err := stream.Send(&calculatorpb.StreamMatrixResponse{
+			Message: &calculatorpb.StreamMatrixResponse_Row{Row: &calculatorpb.MatrixRow{Values: res.Row(i)}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func matrixPair(req *calculatorpb.MatrixPairRequest) (*linalg.Matrix, *linalg.Matrix, error) {
+	a, err := matrixFromProto("a", req.GetA())
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := matrixFromProto("b", req.GetB())
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, b, nil
+}
+
+func matrixFromProto(name string, m *calculatorpb.Matrix) (*linalg.Matrix, error) {
+	if m == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Matrix %s is required", name)
+	}
+	res, err := linalg.New(int(m.GetRows()), int(m.GetCols()), m.GetValues())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Matrix %s: %v", name, err)
+	}
+	return res, nil
+}
+
+func matrixToProto(m *linalg.Matrix) *calculatorpb.Matrix {
+	return &calculatorpb.Matrix{Rows: int32(m.Rows), Cols: int32(m.Cols), Values: m.Data}
+}
+
+// checkFinite fails with OUT_OF_RANGE if an element of a result overflowed.
+func checkFinite(m *linalg.Matrix) error {
+	for i, v := range m.Data {
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return status.Errorf(codes.OutOfRange, "Element (%d, %d) of the result overflows a double", i/m.Cols+1, i%m.Cols+1)
+		}
+	}
+	return nil
+}
+
+// linalgError converts an error of the linalg package to a status.
+func linalgError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	if errors.Is(err, linalg.ErrDimensions) || errors.Is(err, linalg.ErrSingular) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return err
+}
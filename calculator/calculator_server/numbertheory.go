@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"grpc-go-course/calculator/calculatorpb"
+	"grpc-go-course/calculator/decimal"
+	"math"
+	"math/big"
+)
+
+// maxResultDigits bounds the size of the results of the number theory RPCs.
+const maxResultDigits = 100000
+
+func (s *server) Gcd(ctx context.Context, req *calculatorpb.BigIntegersRequest) (*calculatorpb.BigIntegerResponse, error) {
+	nums, err := parseIntegers(req.GetNumbers())
+	if err != nil {
+		return nil, err
+	}
+	gcd := new(big.Int)
+	for _, n := range nums {
+		gcd.GCD(nil, nil, gcd, n.Abs(n))
+	}
+	return integerResponse(gcd)
+}
+
+func (s *server) Lcm(ctx context.Context, req *calculatorpb.BigIntegersRequest) (*calculatorpb.BigIntegerResponse, error) {
+	nums, err := parseIntegers(req.GetNumbers())
+	if err != nil {
+		return nil, err
+	}
+	lcm := big.NewInt(1)
+	gcd := new(big.Int)
+	for _, n := range nums {
+		if n.Sign() == 0 {
+			return integerResponse(n)
+		}
+		// lcm(a, b) = a / gcd(a, b) * b
+		gcd.GCD(nil, nil, lcm, n.Abs(n))
+		lcm.Quo(lcm, gcd).Mul(lcm, n)
+		if tooManyDigits(lcm) {
+			return nil, resultOutOfRange()
+		}
+	}
+	return integerResponse(lcm)
+}
+
+func (s *server) ModPow(ctx context.Context, req *calculatorpb.ModPowRequest) (*calculatorpb.BigIntegerResponse, error) {
+	nums, err := parseIntegers([]string{req.GetBase(), req.GetExponent(), req.GetModulus()})
+	if err != nil {
+		return nil, err
+	}
+	base, exp, mod := nums[0], nums[1], nums[2]
+	if mod.Sign() <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Modulus must be positive, got %v", mod)
+	}
+	if exp.Sign() < 0 {
+		if base.ModInverse(base.Mod(base, mod), mod) == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Base has no inverse modulo %v, so it can't be raised to a negative exponent", mod)
+		}
+		exp.Neg(exp)
+	}
+	return integerResponse(new(big.Int).Exp(base.Mod(base, mod), exp, mod))
+}
+
+func (s *server) ModInverse(ctx context.Context, req *calculatorpb.ModInverseRequest) (*calculatorpb.BigIntegerResponse, error) {
+	nums, err := parseIntegers([]string{req.GetNumber(), req.GetModulus()})
+	if err != nil {
+		return nil, err
+	}
+	n, mod := nums[0], nums[1]
+	if mod.Sign() <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Modulus must be positive, got %v", mod)
+	}
+	inv := new(big.Int).ModInverse(n.Mod(n, mod), mod)
+	if inv == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v has no inverse modulo %v", req.GetNumber(), mod)
+	}
+	return integerResponse(inv)
+}
+
+func (s *server) Factorial(ctx context.Context, req *calculatorpb.FactorialRequest) (*calculatorpb.BigIntegerResponse, error) {
+	n := req.GetN()
+	if n < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Received a negative number %v", n)
+	}
+	if log10Factorial(n) >= maxResultDigits {
+		return nil, resultOutOfRange()
+	}
+	return integerResponse(new(big.Int).MulRange(1, n))
+}
+
+func (s *server) Binomial(ctx context.Context, req *calculatorpb.BinomialRequest) (*calculatorpb.BigIntegerResponse, error) {
+	n, k := req.GetN(), req.GetK()
+	if n < 0 || k < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "n and k must not be negative, got %v and %v", n, k)
+	}
+	if k > n {
+		return integerResponse(new(big.Int))
+	}
+	if k > n-k {
+		k = n - k
+	}
+	// n choose k <= (e * n / k)^k
+	if k > 0 && float64(k)*math.Log10(math.E*float64(n)/float64(k)) >= maxResultDigits {
+		return nil, resultOutOfRange()
+	}
+	return integerResponse(new(big.Int).Binomial(n, k))
+}
+
+// log10Factorial approximates the base 10 logarithm of n!, which is one less
+// than its number of digits.
+func log10Factorial(n int64) float64 {
+	lg, _ := math.Lgamma(float64(n) + 1)
+	return lg / math.Ln10
+}
+
+// parseIntegers parses the operands of a number theory RPC, failing with
+// INVALID_ARGUMENT on the first one that isn't an integer.
+func parseIntegers(ss []string) ([]*big.Int, error) {
+	if len(ss) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "No numbers given")
+	}
+	nums := make([]*big.Int, len(ss))
+	for i, s := range ss {
+		n, err := decimal.ParseInt(s)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Number %d: %v", i+1, err)
+		}
+		nums[i] = n
+	}
+	return nums, nil
+}
+
+func tooManyDigits(n *big.Int) bool {
+	// Every 3.33 bits make a decimal digit.
+	return float64(n.BitLen()) > maxResultDigits*math.Log2(10)
+}
+
+func resultOutOfRange() error {
+	return status.Errorf(codes.OutOfRange, "Result would have more than %d digits", maxResultDigits)
+}
+
+func integerResponse(n *big.Int) (*calculatorpb.BigIntegerResponse, error) {
+	return &calculatorpb.BigIntegerResponse{Result: n.String()}, nil
+}
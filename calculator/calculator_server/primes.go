@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"grpc-go-course/calculator/calculatorpb"
+	"grpc-go-course/calculator/primes"
+)
+
+// primesBatchSize is how many primes GeneratePrimes sends per message.
+const primesBatchSize = 1000
+
+func (s *server) IsPrime(ctx context.Context, req *calculatorpb.IsPrimeRequest) (*calculatorpb.IsPrimeResponse, error) {
+	return &calculatorpb.IsPrimeResponse{Prime: primes.IsPrime(req.GetNumber())}, nil
+}
+
+func (s *server) GeneratePrimes(req *calculatorpb.GeneratePrimesRequest, stream calculatorpb.CalculatorService_GeneratePrimesServer) error {
+	start, end := req.GetStart(), req.GetEnd()
+	if start > end {
+		return status.Errorf(codes.InvalidArgument, "Start %d is after end %d", start, end)
+	}
+
+	ctx := stream.Context()
+	batch := make([]uint64, 0, primesBatchSize)
+	err := primes.Range(ctx, start, end, func(p uint64) error {
+		batch = append(batch, p)
+		if len(batch) < primesBatchSize {
+			return nil
+		}
+		err := stream.Send(&calculatorpb.GeneratePrimesResponse{Primes: batch})
+		batch = batch[:0]
+		return err
+	})
+	if err != nil && ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	if err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return stream.Send(&calculatorpb.GeneratePrimesResponse{Primes: batch})
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"grpc-go-course/calculator/bigmath"
+	"grpc-go-course/calculator/calculatorpb"
+	"grpc-go-course/calculator/decimal"
+	"math"
+	"math/big"
+)
+
+const (
+	defaultRootDegree    = 2
+	maxRootDegree        = 1000
+	defaultRootPrecision = 20
+	maxRootPrecision     = 1000
+)
+
+func (s *server) Root(ctx context.Context, req *calculatorpb.RootRequest) (*calculatorpb.RootResponse, error) {
+	degree := int(req.GetDegree())
+	if degree == 0 {
+		degree = defaultRootDegree
+	}
+	if degree < 1 || degree > maxRootDegree {
+		return nil, status.Errorf(codes.InvalidArgument, "Degree must be between 1 and %d, got %d", maxRootDegree, degree)
+	}
+	digits := int(req.GetPrecision())
+	if digits == 0 {
+		digits = defaultRootPrecision
+	}
+	if digits < 1 || digits > maxRootPrecision {
+		return nil, status.Errorf(codes.InvalidArgument, "Precision must be between 1 and %d digits, got %d", maxRootPrecision, digits)
+	}
+	// Bits of mantissa for the requested digits, plus guard bits.
+	prec := uint(float64(digits)*math.Log2(10)) + 16
+
+	a := new(big.Float).SetPrec(prec)
+	switch num := req.GetNumber().(type) {
+	case *calculatorpb.RootRequest_Value:
+		if math.IsNaN(num.Value) || math.IsInf(num.Value, 0) {
+			return nil, status.Errorf(codes.InvalidArgument, "Number is not finite: %v", num.Value)
+		}
+		a.SetFloat64(num.Value)
+	case *calculatorpb.RootRequest_Decimal:
+		r, err := decimal.Parse(num.Decimal)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Number: %v", err)
+		}
+		a.SetRat(r)
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "Number is required")
+	}
+
+	negative := a.Sign() < 0
+	if negative && degree%2 == 0 && !req.GetAllowComplex() {
+		return nil, status.Errorf(codes.InvalidArgument, "Received a negative number %v, which has no real root of degree %d", a.Text('g', digits), degree)
+	}
+
+	root := bigmath.Root(new(big.Float).Abs(a), degree, prec)
+	res := &calculatorpb.RootResponse{}
+	switch {
+	case !negative:
+		setRootReal(res, root, digits)
+	case degree%2 == 1:
+		setRootReal(res, root.Neg(root), digits)
+	default:
+		// The principal root of a negative number is |a|^(1/n) * e^(iπ/n).
+		if degree == 2 {
+			setRootReal(res, new(big.Float), digits)
+		} else {
+			angle := bigmath.Pi(prec)
+			angle.Quo(angle, big.NewFloat(float64(degree)))
+			sin, cos := bigmath.SinCos(angle, prec)
+			setRootReal(res, cos.Mul(cos, root), digits)
+			root.Mul(root, sin)
+		}
+		res.Imaginary, _ = root.Float64()
+		res.ImaginaryDecimal = root.Text('g', digits)
+	}
+	return res, nil
+}
+
+func setRootReal(res *calculatorpb.RootResponse, x *big.Float, digits int) {
+	res.Value, _ = x.Float64()
+	res.Decimal = x.Text('g', digits)
+}
@@ -2,19 +2,51 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"google.golang.org/genproto/googleapis/longrunning"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"grpc-go-course/admin"
+	"grpc-go-course/cache"
 	"grpc-go-course/calculator/calculatorpb"
+	"grpc-go-course/calculator/primes"
+	"grpc-go-course/calculator/units"
+	"grpc-go-course/certs"
+	"grpc-go-course/db"
+	"grpc-go-course/faults"
+	"grpc-go-course/history"
+	"grpc-go-course/logging"
+	"grpc-go-course/operations"
 	"io"
 	"log"
 	"math"
 	"net"
+	"strings"
+	"time"
 )
 
-type server struct{}
+// cachedMethods are the pure RPCs, whose responses are cached, with the type
+// of their requests.
+var cachedMethods = map[string]proto.Message{
+	"/calculator.CalculatorService/SquareRoot":           &calculatorpb.SquareRootRequest{},
+	"/calculator.CalculatorService/Root":                 &calculatorpb.RootRequest{},
+	"/calculator.CalculatorService/IsPrime":              &calculatorpb.IsPrimeRequest{},
+	"/calculator.CalculatorService/DecomposePrimeNumber": &calculatorpb.PrimeNumberDecompositionRequest{},
+	"/calculator.CalculatorService/Factorial":            &calculatorpb.FactorialRequest{},
+	"/calculator.CalculatorService/Binomial":             &calculatorpb.BinomialRequest{},
+}
+
+type server struct {
+	ops      *operations.Server
+	units    *units.Registry
+	sessions *sessions
+}
 
 func (s *server) SquareRoot(ctx context.Context, req *calculatorpb.SquareRootRequest) (*calculatorpb.SquareRootResponse, error) {
 	num := req.GetNumber()
@@ -30,6 +62,7 @@ func (s *server) SquareRoot(ctx context.Context, req *calculatorpb.SquareRootReq
 
 func (s *server) FindMaximum(stream calculatorpb.CalculatorService_FindMaximumServer) error {
 	var curMax int64
+	seen := false
 	for {
 		req, err := stream.Recv()
 		if err == io.EOF {
@@ -40,8 +73,8 @@ func (s *server) FindMaximum(stream calculatorpb.CalculatorService_FindMaximumSe
 		}
 
 		curNum := req.GetNumber()
-		if curNum > curMax {
-			curMax = curNum
+		if !seen || curNum > curMax {
+			curMax, seen = curNum, true
 			if err := stream.Send(&calculatorpb.FindMaximumResponse{MaxNumber: curMax}); err != nil {
 				return err
 			}
@@ -50,64 +83,167 @@ func (s *server) FindMaximum(stream calculatorpb.CalculatorService_FindMaximumSe
 }
 
 func (s *server) ComputeAverage(stream calculatorpb.CalculatorService_ComputeAverageServer) error {
-	var nums []int64
+	var sum, count int64
 	for {
 		req, err := stream.Recv()
 		if err == io.EOF {
-			var sum int64
-			for _, n := range nums {
-				sum += n
+			if count == 0 {
+				return status.Errorf(codes.InvalidArgument, "Received no numbers")
 			}
-			mean := float64(sum) / float64(len(nums))
+			mean := float64(sum) / float64(count)
 			return stream.SendAndClose(&calculatorpb.ComputeAverageResponse{Mean: mean})
 		}
 		if err != nil {
-			log.Fatalf("Failed to read client stream %v", err)
+			return err
 		}
-		nums = append(nums, req.GetNumber())
+		n := req.GetNumber()
+		if (n > 0 && sum > math.MaxInt64-n) || (n < 0 && sum < math.MinInt64-n) {
+			return status.Errorf(codes.OutOfRange, "Sum of the numbers overflows int64 after %d numbers", count+1)
+		}
+		sum += n
+		count++
 	}
 }
 
 func (s *server) DecomposePrimeNumber(req *calculatorpb.PrimeNumberDecompositionRequest, stream calculatorpb.CalculatorService_DecomposePrimeNumberServer) error {
-	k := int64(2)
+	ctx := stream.Context()
 	n := req.GetNumber()
-	for n > 1 {
-		if n%k == 0 {
-			res := &calculatorpb.PrimeNumberDecompositionResponse{Result: k}
-			if err := stream.Send(res); err != nil {
-				return err
-			}
-			n = n / k
-		} else {
-			k++
-		}
-
+	if n <= 0 {
+		return status.Errorf(codes.InvalidArgument, "Received a non-positive number %v", n)
+	}
+	err := primes.Factor(ctx, uint64(n), func(p uint64) error {
+		return stream.Send(&calculatorpb.PrimeNumberDecompositionResponse{Result: int64(p)})
+	})
+	if err != nil && ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
 	}
-	return nil
+	return err
 }
 
 func (s *server) Sum(ctx context.Context, req *calculatorpb.SumRequest) (*calculatorpb.SumResponse, error) {
-	fmt.Println("Sum function was invoked")
-	sum := req.GetNum_1() + req.GetNum_2()
-	res := &calculatorpb.SumResponse{Result: sum}
+	logging.Debugf("Sum function was invoked")
+	sum := int64(req.GetNum_1()) + int64(req.GetNum_2())
+	if sum > math.MaxInt32 || sum < math.MinInt32 {
+		return nil, status.Errorf(codes.OutOfRange, "Sum of %d and %d overflows int32", req.GetNum_1(), req.GetNum_2())
+	}
+	res := &calculatorpb.SumResponse{Result: int32(sum)}
 	return res, nil
 }
 
 func main() {
-	address := "0.0.0.0:50051"
-	listener, err := net.Listen("tcp", address)
+	address := flag.String("addr", "0.0.0.0:50051", "address to listen on")
+	var faultCfg faults.Config
+	faultCfg.RegisterFlags(flag.CommandLine)
+	var adminFlags admin.Flags
+	adminFlags.Register(flag.CommandLine)
+	maxOperations := flag.Int("max-operations", 16, "maximum number of long-running operations running at once")
+	operationTimeout := flag.Duration("operation-timeout", time.Hour, "how long a long-running operation may run before it fails with DEADLINE_EXCEEDED, 0 for no limit")
+	operationRetention := flag.Duration("operation-retention", 24*time.Hour, "how long finished operations are kept")
+	unitsFile := flag.String("units", "", "file of units to add to the builtin ones, in the format of the builtin table of the units package")
+	historyStore := flag.String("history", "memory", "where RPCs are recorded for ListHistory: memory, mongo or none")
+	historyMaxRecords := flag.Int("history-max-records", 100000, "number of RPCs the memory history keeps")
+	mongoURI := flag.String("mongo-uri", "mongodb://localhost:27017", "MongoDB connection string of the mongo history")
+	cacheSize := flag.Int("cache-size", 10000, "number of responses of pure RPCs cached, 0 to disable the cache")
+	cacheBytes := flag.Int("cache-bytes", 64<<20, "maximum size in bytes of the cached requests and responses, responses larger than that are not cached")
+	cacheTTL := flag.Duration("cache-ttl", time.Hour, "how long responses are cached")
+	maxSessions := flag.Int("max-sessions", 1000, "maximum number of calculator sessions, attached to a stream or not")
+	sessionGrace := flag.Duration("session-grace", 5*time.Minute, "how long a session can be resumed after its stream ends")
+	certFile := flag.String("tls-cert", "", "certificate to serve TLS with, empty for plaintext")
+	keyFile := flag.String("tls-key", "", "private key of -tls-cert")
+	clientCAFile := flag.String("tls-client-ca", "", "CA certificate verifying the client certificates that identify callers in the history")
+	trustedGateways := flag.String("trusted-gateways", "", "comma separated common names of the gateway certificates trusted to forward the identity of their clients")
+	flag.Var(logging.LevelFlag{}, "log-level", "minimum level of the messages logged: debug, info, warn or error")
+	flag.Parse()
+
+	// The admin port gets the same credentials
+	var credsOpts []grpc.ServerOption
+	if *certFile != "" {
+		cfg, err := certs.ServerConfig(*certFile, *keyFile, *clientCAFile)
+		if err != nil {
+			log.Fatalf("Failed loading certs %v", err)
+		}
+		credsOpts = append(credsOpts, grpc.Creds(credentials.NewTLS(cfg)))
+	}
+
+	unitRegistry := units.New()
+	if *unitsFile != "" {
+		if err := unitRegistry.LoadFile(*unitsFile); err != nil {
+			log.Fatalf("Failed to load units %v", err)
+		}
+	}
+
+	var store history.Store
+	switch *historyStore {
+	case "memory":
+		if *historyMaxRecords < 1 {
+			log.Fatalf("Invalid -history-max-records %d", *historyMaxRecords)
+		}
+		store = history.NewMemoryStore(*historyMaxRecords)
+	case "mongo":
+		client, err := db.InitClient(*mongoURI)
+		if err != nil {
+			log.Fatalf("Failed to connect to mongoDB %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		store, err = history.NewMongoStore(ctx, client.Database("mydb").Collection("calculator_history"))
+		cancel()
+		if err != nil {
+			log.Fatalf("Failed to set up the history collection %v", err)
+		}
+	case "none":
+	default:
+		log.Fatalf("Unknown -history %q, want memory, mongo or none", *historyStore)
+	}
+
+	listener, err := net.Listen("tcp", *address)
 	if err != nil {
 		log.Fatalf("Failed to listen %v", err)
 	}
 
-	s := grpc.NewServer()
+	// The history records the RPCs failed by injected faults and the cache
+	// hits too. Faults are injected before the cache is looked up, so that
+	// cached RPCs fail like the others.
+	var historyOpts, cacheOpts []grpc.ServerOption
+	if store != nil {
+		recorder := history.NewRecorder(store, "calculator.CalculatorService", "calculator.LinearAlgebraService")
+		// Anyone can list the history, and a session ID is all it takes to
+		// resume a session started without a client certificate.
+		recorder.Exclude("/calculator.CalculatorService/Session")
+		if *trustedGateways != "" {
+			recorder.TrustGateways(strings.Split(*trustedGateways, ",")...)
+		}
+		historyOpts = recorder.ServerOptions()
+	}
+	if *cacheSize > 0 {
+		cacheOpts = cache.New(*cacheSize, *cacheBytes, *cacheTTL, cachedMethods).ServerOptions()
+	}
+	opts := credsOpts
+	opts = append(opts, historyOpts...)
+	opts = append(opts, faultCfg.ServerOptions()...)
+	opts = append(opts, cacheOpts...)
+	s := grpc.NewServer(opts...)
 
-	calculatorpb.RegisterCalculatorServiceServer(s, &server{})
+	healthSrv := health.NewServer()
+	ops := operations.NewServer(operations.NewMemoryStore(), *maxOperations, *operationTimeout, *operationRetention)
+	calcSessions := newSessions(*maxSessions, *sessionGrace)
+	calculatorpb.RegisterCalculatorServiceServer(s, &server{ops: ops, units: unitRegistry, sessions: calcSessions})
+	calculatorpb.RegisterLinearAlgebraServiceServer(s, &linalgServer{})
+	if store != nil {
+		calculatorpb.RegisterHistoryServiceServer(s, &historyServer{store: store})
+	}
+	longrunning.RegisterOperationsServer(s, ops)
+	healthpb.RegisterHealthServer(s, healthSrv)
+	drainer := admin.NewDrainer(healthSrv, "calculator.CalculatorService", "calculator.LinearAlgebraService", "calculator.HistoryService", "google.longrunning.Operations")
 
-	// Register reflection service on gRPC server
-	reflection.Register(s)
+	// Reflection and channelz are only served on the admin port.
+	if _, err := adminFlags.Serve(s, healthSrv, credsOpts...); err != nil {
+		log.Fatalf("Failed to start admin port %v", err)
+	}
+	if _, err := adminFlags.ServeHTTP(admin.HTTPConfig{Flags: flag.CommandLine, Drainer: drainer}); err != nil {
+		log.Fatalf("Failed to start admin HTTP server %v", err)
+	}
 
-	fmt.Printf("Server started at %s\n", address)
+	logging.Infof("Server started at %s", *address)
 
 	if err := s.Serve(listener); err != nil {
 		log.Fatalf("Failed to serve %v", err)
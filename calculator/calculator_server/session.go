@@ -0,0 +1,204 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"grpc-go-course/calculator/calculatorpb"
+	"grpc-go-course/calculator/rpn"
+	"grpc-go-course/history"
+	"grpc-go-course/logging"
+	"io"
+	"sync"
+	"time"
+)
+
+var sessionOps = map[calculatorpb.SessionRequest_Operation]rpn.Op{
+	calculatorpb.SessionRequest_ADD:      rpn.Add,
+	calculatorpb.SessionRequest_SUBTRACT: rpn.Subtract,
+	calculatorpb.SessionRequest_MULTIPLY: rpn.Multiply,
+	calculatorpb.SessionRequest_DIVIDE:   rpn.Divide,
+	calculatorpb.SessionRequest_POWER:    rpn.Power,
+	calculatorpb.SessionRequest_NEGATE:   rpn.Negate,
+	calculatorpb.SessionRequest_SQRT:     rpn.Sqrt,
+	calculatorpb.SessionRequest_DUP:      rpn.Dup,
+	calculatorpb.SessionRequest_SWAP:     rpn.Swap,
+	calculatorpb.SessionRequest_CLEAR:    rpn.Clear,
+}
+
+// sessions holds the sessions of the Session RPC, attached to a stream or
+// waiting for one to resume them.
+type sessions struct {
+	mu    sync.Mutex
+	m     map[string]*session
+	max   int
+	grace time.Duration
+}
+
+type session struct {
+	id string
+	// caller is the authenticated client that started the session, the
+	// only one allowed to resume it, or empty if it had no certificate.
+	caller string
+	// mu guards the fields below.
+	mu      sync.Mutex
+	machine rpn.Machine
+	seq     uint64
+	// owner identifies the stream the session is attached to, 0 if none.
+	owner uint64
+	// streams counts the streams the session was attached to, to give each
+	// a new owner.
+	streams uint64
+}
+
+func newSessions(max int, grace time.Duration) *sessions {
+	return &sessions{m: make(map[string]*session), max: max, grace: grace}
+}
+
+// attach attaches the session with the given ID, or a new one if id is empty,
+// to a stream of caller, detaching it from any other, and returns the owner
+// identifying the stream. A session started by an authenticated caller can
+// only be resumed by the same caller; to others it does not exist.
+func (ss *sessions) attach(id, caller string) (*session, uint64, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	var s *session
+	if id == "" {
+		if len(ss.m) >= ss.max {
+			return nil, 0, status.Errorf(codes.ResourceExhausted, "Too many sessions, at most %d", ss.max)
+		}
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return nil, 0, err
+		}
+		s = &session{id: hex.EncodeToString(b), caller: caller}
+		ss.m[s.id] = s
+	} else {
+		var ok bool
+		if s, ok = ss.m[id]; !ok || s.caller != "" && s.caller != caller {
+			return nil, 0, status.Errorf(codes.NotFound, "Session %q not found, or expired", id)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.streams++
+	s.owner = s.streams
+	return s, s.owner, nil
+}
+
+// detach detaches s from the stream identified by owner, if it is still
+// attached to it, and forgets s if no stream resumes it within the grace
+// period.
+func (ss *sessions) detach(s *session, owner uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.owner != owner {
+		return
+	}
+	s.owner = 0
+	detached := s.streams
+	time.AfterFunc(ss.grace, func() {
+		ss.mu.Lock()
+		defer ss.mu.Unlock()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		// The session was not resumed since it was detached.
+		if s.owner == 0 && s.streams == detached {
+			delete(ss.m, s.id)
+			logging.Debugf("Session %s expired", s.id)
+		}
+	})
+}
+
+func (s *server) Session(stream calculatorpb.CalculatorService_SessionServer) error {
+	req, err := stream.Recv()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var id string
+	if start := req.GetStart(); start != nil {
+		id = start.GetSessionId()
+		req = nil
+	}
+	caller, _ := history.AuthenticatedCaller(stream.Context())
+	sess, owner, err := s.sessions.attach(id, caller)
+	if err != nil {
+		return err
+	}
+	defer s.sessions.detach(sess, owner)
+
+	// A start command is answered with the state of the session, so that
+	// the client learns its ID or where it left off.
+	for {
+		if req != nil && req.GetStart() != nil {
+			return status.Errorf(codes.InvalidArgument, "Start is only valid as the first command")
+		}
+		res, err := sess.apply(owner, req)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(res); err != nil {
+			return err
+		}
+
+		req, err = stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// apply applies a command, if req is not nil, and returns the state of the
+// session. Commands that fail leave the state unchanged and return it with
+// the error.
+func (s *session) apply(owner uint64, req *calculatorpb.SessionRequest) (*calculatorpb.SessionResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.owner != owner {
+		return nil, status.Errorf(codes.Aborted, "Session %s was resumed by another stream", s.id)
+	}
+
+	res := &calculatorpb.SessionResponse{SessionId: s.id}
+	if req != nil {
+		var err error
+		switch cmd := req.GetCommand().(type) {
+		case *calculatorpb.SessionRequest_Push:
+			err = s.machine.Push(cmd.Push)
+		case *calculatorpb.SessionRequest_Pop_:
+			err = s.machine.Pop()
+		case *calculatorpb.SessionRequest_Operation_:
+			op, ok := sessionOps[cmd.Operation]
+			if !ok {
+				return nil, status.Errorf(codes.InvalidArgument, "Unknown operation %v", cmd.Operation)
+			}
+			err = s.machine.Apply(op)
+		case *calculatorpb.SessionRequest_Store:
+			err = s.machine.Store(cmd.Store)
+		case *calculatorpb.SessionRequest_Recall:
+			err = s.machine.Recall(cmd.Recall)
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "Empty command")
+		}
+		if err != nil {
+			res.Error = err.Error()
+		} else {
+			s.seq++
+		}
+	}
+
+	res.Seq = s.seq
+	res.Stack = append([]float64(nil), s.machine.Stack...)
+	res.Registers = make(map[string]float64, len(s.machine.Registers))
+	for name, v := range s.machine.Registers {
+		res.Registers[name] = v
+	}
+	return res, nil
+}
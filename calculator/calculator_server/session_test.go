@@ -0,0 +1,196 @@
+package main
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"grpc-go-course/calculator/calculatorpb"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func push(v float64) *calculatorpb.SessionRequest {
+	return &calculatorpb.SessionRequest{Command: &calculatorpb.SessionRequest_Push{Push: v}}
+}
+
+func operation(op calculatorpb.SessionRequest_Operation) *calculatorpb.SessionRequest {
+	return &calculatorpb.SessionRequest{Command: &calculatorpb.SessionRequest_Operation_{Operation: op}}
+}
+
+// run applies reqs to s as the stream owner, and returns the last state.
+func run(t *testing.T, s *session, owner uint64, reqs ...*calculatorpb.SessionRequest) *calculatorpb.SessionResponse {
+	t.Helper()
+	var res *calculatorpb.SessionResponse
+	for _, req := range reqs {
+		var err error
+		if res, err = s.apply(owner, req); err != nil {
+			t.Fatalf("apply(%v) failed: %v", req, err)
+		}
+		if res.GetError() != "" {
+			t.Fatalf("apply(%v) failed: %s", req, res.GetError())
+		}
+	}
+	return res
+}
+
+// waitExpired waits for the session id to expire. Resuming it to find out
+// would keep it alive.
+func waitExpired(t *testing.T, ss *sessions, id string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		ss.mu.Lock()
+		_, ok := ss.m[id]
+		ss.mu.Unlock()
+		if !ok {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("session %s did not expire", id)
+		}
+		time.Sleep(ss.grace / 2)
+	}
+}
+
+func TestSessionCommands(t *testing.T) {
+	ss := newSessions(10, time.Minute)
+	s, owner, err := ss.attach("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := run(t, s, owner,
+		push(3), push(4), operation(calculatorpb.SessionRequest_ADD),
+		&calculatorpb.SessionRequest{Command: &calculatorpb.SessionRequest_Store{Store: "x"}},
+		&calculatorpb.SessionRequest{Command: &calculatorpb.SessionRequest_Recall{Recall: "x"}},
+		operation(calculatorpb.SessionRequest_MULTIPLY),
+	)
+	if res.GetSessionId() != s.id || res.GetSeq() != 6 || !reflect.DeepEqual(res.GetStack(), []float64{49}) || res.GetRegisters()["x"] != 7 {
+		t.Errorf("the session is %v, want seq 6, stack [49] and x = 7", res)
+	}
+
+	// A failed command reports its error with the unchanged state.
+	res, err = s.apply(owner, &calculatorpb.SessionRequest{Command: &calculatorpb.SessionRequest_Pop_{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = s.apply(owner, operation(calculatorpb.SessionRequest_ADD))
+	if err != nil || res.GetError() == "" || res.GetSeq() != 7 || len(res.GetStack()) != 0 {
+		t.Errorf("add on an empty stack = %v, %v, want an error at seq 7 with an empty stack", res, err)
+	}
+
+	for _, req := range []*calculatorpb.SessionRequest{
+		{},
+		operation(calculatorpb.SessionRequest_Operation(99)),
+	} {
+		if _, err := s.apply(owner, req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("apply(%v) = %v, want code %v", req, err, codes.InvalidArgument)
+		}
+	}
+}
+
+func TestSessionResume(t *testing.T) {
+	ss := newSessions(10, 50*time.Millisecond)
+	s, owner, err := ss.attach("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(t, s, owner, push(1), push(2))
+	ss.detach(s, owner)
+
+	// Within the grace period, the session resumes where it left off.
+	resumed, owner2, err := ss.attach(s.id, "")
+	if err != nil {
+		t.Fatalf("resuming within the grace period failed: %v", err)
+	}
+	if res := run(t, resumed, owner2, operation(calculatorpb.SessionRequest_ADD)); res.GetSeq() != 3 || !reflect.DeepEqual(res.GetStack(), []float64{3}) {
+		t.Errorf("the resumed session is %v, want seq 3 and stack [3]", res)
+	}
+
+	// A stream resuming a session in use takes it over.
+	resumed, owner3, err := ss.attach(s.id, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resumed.apply(owner2, push(4)); status.Code(err) != codes.Aborted {
+		t.Errorf("the stream the session was taken from got %v, want code %v", err, codes.Aborted)
+	}
+	// Its end leaves the session to the new stream.
+	ss.detach(resumed, owner2)
+	time.Sleep(2 * ss.grace)
+	if _, err := resumed.apply(owner3, push(4)); err != nil {
+		t.Errorf("the session expired while attached: %v", err)
+	}
+
+	// The grace period passed without a stream resuming it, it is gone.
+	ss.detach(resumed, owner3)
+	waitExpired(t, ss, s.id)
+	if _, _, err := ss.attach(s.id, ""); status.Code(err) != codes.NotFound {
+		t.Errorf("resuming after the grace period = %v, want code %v", err, codes.NotFound)
+	}
+}
+
+func TestSessionMax(t *testing.T) {
+	ss := newSessions(2, 20*time.Millisecond)
+	a, ownerA, err := ss.attach("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ss.attach("", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ss.attach("", ""); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("a third session = %v, want code %v", err, codes.ResourceExhausted)
+	}
+	// Existing sessions can still be resumed.
+	if _, ownerA, err = ss.attach(a.id, ""); err != nil {
+		t.Errorf("resuming a session when full failed: %v", err)
+	}
+
+	// Detached sessions count until they expire.
+	ss.detach(a, ownerA)
+	if _, _, err := ss.attach("", ""); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("a new session while one awaits resumption = %v, want code %v", err, codes.ResourceExhausted)
+	}
+	waitExpired(t, ss, a.id)
+	if _, _, err := ss.attach("", ""); err != nil {
+		t.Errorf("a new session once one expired failed: %v", err)
+	}
+}
+
+func TestSessionCaller(t *testing.T) {
+	ss := newSessions(10, time.Minute)
+	alice, owner, err := ss.attach("", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss.detach(alice, owner)
+	anyone, owner, err := ss.attach("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss.detach(anyone, owner)
+
+	tests := []struct {
+		id, caller string
+		want       codes.Code
+	}{
+		// The session of an authenticated caller is theirs only.
+		{alice.id, "bob", codes.NotFound},
+		{alice.id, "", codes.NotFound},
+		{alice.id, "alice", codes.OK},
+		// Anyone who knows its ID can resume a session started without a
+		// certificate.
+		{anyone.id, "", codes.OK},
+		{anyone.id, "bob", codes.OK},
+		{"unknown", "alice", codes.NotFound},
+	}
+	for _, tt := range tests {
+		s, owner, err := ss.attach(tt.id, tt.caller)
+		if status.Code(err) != tt.want {
+			t.Errorf("attach(%s, %q) = %v, want code %v", tt.id, tt.caller, err, tt.want)
+		}
+		if err == nil {
+			ss.detach(s, owner)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"grpc-go-course/calculator/calculatorpb"
+	"grpc-go-course/calculator/stats"
+	"io"
+	"math"
+)
+
+// defaultPercentiles are estimated by ComputeStatistics unless the first
+// request asks for others.
+var defaultPercentiles = []float64{50, 90, 95, 99}
+
+// maxPercentiles bounds how many percentiles a ComputeStatistics call can
+// estimate.
+const maxPercentiles = 20
+
+func (s *server) ComputeStatistics(stream calculatorpb.CalculatorService_ComputeStatisticsServer) error {
+	var summary *stats.Summary
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if summary == nil {
+			percentiles := req.GetPercentiles()
+			if len(percentiles) == 0 {
+				percentiles = defaultPercentiles
+			}
+			if len(percentiles) > maxPercentiles {
+				return status.Errorf(codes.InvalidArgument, "At most %d percentiles can be estimated, got %d", maxPercentiles, len(percentiles))
+			}
+			for _, p := range percentiles {
+				if !(p >= 0 && p <= 100) {
+					return status.Errorf(codes.InvalidArgument, "Percentile %v is not between 0 and 100", p)
+				}
+			}
+			summary = stats.New(percentiles...)
+		}
+
+		n := req.GetNumber()
+		if math.IsNaN(n) || math.IsInf(n, 0) {
+			return status.Errorf(codes.InvalidArgument, "Number %d is not finite: %v", summary.Count()+1, n)
+		}
+		summary.Add(n)
+	}
+
+	if summary == nil {
+		return status.Errorf(codes.InvalidArgument, "Received no numbers")
+	}
+	res := &calculatorpb.ComputeStatisticsResponse{
+		Count:    summary.Count(),
+		Mean:     summary.Mean(),
+		Variance: summary.Variance(),
+		Stddev:   summary.StdDev(),
+		Min:      summary.Min(),
+		Max:      summary.Max(),
+	}
+	for _, p := range summary.Percentiles() {
+		res.Percentiles = append(res.Percentiles, &calculatorpb.Percentile{Percentile: p.Percentile, Value: p.Value})
+	}
+	return stream.SendAndClose(res)
+}
@@ -0,0 +1,10 @@
+package calculatorpb
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI document of the calculator services,
+// calculator.swagger.json, embedded so that the gateway does not depend on
+// its working directory.
+//
+//go:embed calculator.swagger.json
+var OpenAPI []byte
@@ -0,0 +1,121 @@
+// Package decimal parses and formats exact decimal numbers as big.Rat, for
+// calculations that must not lose precision to floating point, such as
+// amounts of money.
+package decimal
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+)
+
+const (
+	// MaxLength is the longest decimal string accepted by Parse.
+	MaxLength = 1000
+	// MaxExponent bounds the exponent of numbers written in scientific
+	// notation, so that "1e1000000000" can't exhaust the memory of the server.
+	MaxExponent = 1000
+	// MaxScale is the largest number of digits Format writes after the
+	// decimal point.
+	MaxScale = 1000
+)
+
+var (
+	// ErrSyntax is returned by Parse for strings that are not decimal numbers.
+	ErrSyntax = errors.New("not a decimal number")
+	// ErrNotInteger is returned by ParseInt for strings that are not integers.
+	ErrNotInteger = errors.New("not an integer")
+	// ErrRange is returned by Parse and ParseInt for numbers too long or too large to
+	// handle.
+	ErrRange = errors.New("decimal number out of range")
+)
+
+// pattern matches decimal numbers with an optional sign, fraction and
+// exponent: "42", "-0.5", ".25", "1.5e-3". Fractions such as "1/3", which
+// big.Rat would accept, are not decimal numbers.
+var pattern = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)(?:[eE]([+-]?\d+))?$`)
+
+// Parse parses s as an exact decimal number.
+func Parse(s string) (*big.Rat, error) {
+	if len(s) > MaxLength {
+		return nil, fmt.Errorf("%w: longer than %d characters", ErrRange, MaxLength)
+	}
+	m := pattern.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+	if m[2] != "" {
+		exp, err := strconv.Atoi(m[2])
+		if err != nil || exp > MaxExponent || exp < -MaxExponent {
+			return nil, fmt.Errorf("%w: exponent of %q exceeds %d", ErrRange, s, MaxExponent)
+		}
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+	return r, nil
+}
+
+// Format writes r in decimal notation. If r has a finite decimal expansion of
+// at most scale digits after the point, it is written exactly, with no
+// trailing zeros, and exact is true. Otherwise it is rounded half away from
+// zero to scale digits and exact is false. scale is capped at MaxScale.
+func Format(r *big.Rat, scale int) (s string, exact bool) {
+	if scale < 0 {
+		scale = 0
+	}
+	if scale > MaxScale {
+		scale = MaxScale
+	}
+	if digits, ok := fractionDigits(r); ok && digits <= scale {
+		return r.FloatString(digits), true
+	}
+	return r.FloatString(scale), false
+}
+
+// fractionDigits returns the number of digits after the decimal point needed
+// to write r exactly, and whether that number is finite, which is the case
+// when the denominator of r only has 2 and 5 as prime factors.
+func fractionDigits(r *big.Rat) (int, bool) {
+	d := new(big.Int).Set(r.Denom())
+	twos := int(d.TrailingZeroBits())
+	d.Rsh(d, uint(twos))
+
+	five := big.NewInt(5)
+	fives := 0
+	q, m := new(big.Int), new(big.Int)
+	for d.Cmp(big.NewInt(1)) != 0 {
+		q.QuoRem(d, five, m)
+		if m.Sign() != 0 {
+			return 0, false
+		}
+		d, q = q, d
+		fives++
+	}
+	if twos > fives {
+		return twos, true
+	}
+	return fives, true
+}
+
+// integerPattern matches integers in decimal, with an optional sign.
+var integerPattern = regexp.MustCompile(`^[+-]?\d+$`)
+
+// ParseInt parses s as an integer in decimal, of at most MaxLength
+// characters.
+func ParseInt(s string) (*big.Int, error) {
+	if len(s) > MaxLength {
+		return nil, fmt.Errorf("%w: longer than %d characters", ErrRange, MaxLength)
+	}
+	if !integerPattern.MatchString(s) {
+		return nil, fmt.Errorf("%w: %q", ErrNotInteger, s)
+	}
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrNotInteger, s)
+	}
+	return n, nil
+}
@@ -0,0 +1,194 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// constants are the names that can be used without being bound.
+var constants = map[string]float64{
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
+type function struct {
+	// arity is the number of arguments, or -1 for one or more.
+	arity int
+	call  func(args []float64) (float64, error)
+}
+
+func unaryFunc(f func(float64) float64) function {
+	return function{arity: 1, call: func(args []float64) (float64, error) {
+		return f(args[0]), nil
+	}}
+}
+
+var functions = map[string]function{
+	"sqrt": {arity: 1, call: func(args []float64) (float64, error) {
+		if args[0] < 0 {
+			return 0, fmt.Errorf("square root of negative number %v", args[0])
+		}
+		return math.Sqrt(args[0]), nil
+	}},
+	"ln": {arity: 1, call: func(args []float64) (float64, error) {
+		if args[0] <= 0 {
+			return 0, fmt.Errorf("logarithm of non-positive number %v", args[0])
+		}
+		return math.Log(args[0]), nil
+	}},
+	"log10": {arity: 1, call: func(args []float64) (float64, error) {
+		if args[0] <= 0 {
+			return 0, fmt.Errorf("logarithm of non-positive number %v", args[0])
+		}
+		return math.Log10(args[0]), nil
+	}},
+	"abs":   unaryFunc(math.Abs),
+	"exp":   unaryFunc(math.Exp),
+	"floor": unaryFunc(math.Floor),
+	"ceil":  unaryFunc(math.Ceil),
+	"round": unaryFunc(math.Round),
+	"sin":   unaryFunc(math.Sin),
+	"cos":   unaryFunc(math.Cos),
+	"tan":   unaryFunc(math.Tan),
+	"pow": {arity: 2, call: func(args []float64) (float64, error) {
+		return math.Pow(args[0], args[1]), nil
+	}},
+	"min": {arity: -1, call: func(args []float64) (float64, error) {
+		m := args[0]
+		for _, a := range args[1:] {
+			m = math.Min(m, a)
+		}
+		return m, nil
+	}},
+	"max": {arity: -1, call: func(args []float64) (float64, error) {
+		m := args[0]
+		for _, a := range args[1:] {
+			m = math.Max(m, a)
+		}
+		return m, nil
+	}},
+}
+
+// Functions returns the names of the functions expressions can call.
+func Functions() []string {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Eval evaluates the expression, looking up names in vars before the
+// constants pi and e. Errors, such as an unbound name or a division by zero,
+// are of type *Error. The result may be infinite if it overflows a float64.
+func (e *Expr) Eval(vars map[string]float64) (float64, error) {
+	ev := evaluator{src: e.src, vars: vars}
+	return ev.eval(e.root)
+}
+
+type evaluator struct {
+	src  string
+	vars map[string]float64
+}
+
+func (ev *evaluator) errorf(n Node, format string, args ...interface{}) *Error {
+	return &Error{Pos: column(ev.src, n.pos()), Msg: fmt.Sprintf(format, args...)}
+}
+
+func (ev *evaluator) eval(n Node) (float64, error) {
+	switch n := n.(type) {
+	case *numberNode:
+		return n.value, nil
+
+	case *varNode:
+		if v, ok := ev.vars[n.name]; ok {
+			return v, nil
+		}
+		if v, ok := constants[n.name]; ok {
+			return v, nil
+		}
+		return 0, ev.errorf(n, "unbound variable %q", n.name)
+
+	case *unaryNode:
+		x, err := ev.eval(n.x)
+		if err != nil {
+			return 0, err
+		}
+		if n.op == '-' {
+			return -x, nil
+		}
+		return x, nil
+
+	case *binaryNode:
+		x, err := ev.eval(n.x)
+		if err != nil {
+			return 0, err
+		}
+		y, err := ev.eval(n.y)
+		if err != nil {
+			return 0, err
+		}
+		return ev.binary(n, x, y)
+
+	case *callNode:
+		fn, ok := functions[n.name]
+		if !ok {
+			return 0, ev.errorf(n, "unknown function %q, want one of %s", n.name, strings.Join(Functions(), ", "))
+		}
+		if fn.arity >= 0 && len(n.args) != fn.arity || fn.arity < 0 && len(n.args) == 0 {
+			want := fmt.Sprint(fn.arity)
+			if fn.arity < 0 {
+				want = "at least 1"
+			}
+			return 0, ev.errorf(n, "%s takes %s argument(s), got %d", n.name, want, len(n.args))
+		}
+		args := make([]float64, len(n.args))
+		for i, arg := range n.args {
+			v, err := ev.eval(arg)
+			if err != nil {
+				return 0, err
+			}
+			args[i] = v
+		}
+		v, err := fn.call(args)
+		if err != nil {
+			return 0, ev.errorf(n, "%v", err)
+		}
+		if math.IsNaN(v) {
+			return 0, ev.errorf(n, "%s is undefined for these arguments", n.name)
+		}
+		return v, nil
+	}
+	panic(fmt.Sprintf("expr: unexpected node %T", n))
+}
+
+func (ev *evaluator) binary(n *binaryNode, x, y float64) (float64, error) {
+	var v float64
+	switch n.op {
+	case '+':
+		v = x + y
+	case '-':
+		v = x - y
+	case '*':
+		v = x * y
+	case '/':
+		if y == 0 {
+			return 0, ev.errorf(n, "division by zero")
+		}
+		v = x / y
+	case '%':
+		if y == 0 {
+			return 0, ev.errorf(n, "modulo by zero")
+		}
+		v = math.Mod(x, y)
+	case '^':
+		v = math.Pow(x, y)
+	}
+	if math.IsNaN(v) {
+		return 0, ev.errorf(n, "%v %c %v is undefined", x, n.op, y)
+	}
+	return v, nil
+}
@@ -0,0 +1,160 @@
+package expr_test
+
+import (
+	"grpc-go-course/calculator/expr"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	vars := map[string]float64{"x": 3, "y": -2, "e": 10}
+	tests := []struct {
+		src  string
+		want float64
+	}{
+		{"42", 42},
+		{"1e3 + .5", 1000.5},
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 - 4 - 3", 3},
+		{"8 / 4 / 2", 1},
+		{"2 * 7 % 4", 2},
+		{"2 + 7 % 4", 5},
+		// ^ is right associative and binds tighter than unary minus.
+		{"2^3^2", 512},
+		{"(2^3)^2", 64},
+		{"-2^2", -4},
+		{"(-2)^2", 4},
+		{"2^-1", 0.5},
+		{"2^-2^2", 0.0625},
+		{"--2", 2},
+		{"-+-2", 2},
+		{"2 * -3", -6},
+		{"-x^2", -9},
+		{"x * y", -6},
+		// vars shadow the constants.
+		{"pi", math.Pi},
+		{"e", 10},
+		{"sqrt(16) + abs(y)", 6},
+		{"pow(2, 10)", 1024},
+		{"max(1, x, 2)", 3},
+		{"min(4)", 4},
+		{"max(1, min(5, x + 1)) * 2", 8},
+		{"10^400", math.Inf(1)},
+	}
+	for _, tt := range tests {
+		e, err := expr.Parse(tt.src)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.src, err)
+			continue
+		}
+		got, err := e.Eval(vars)
+		if err != nil {
+			t.Errorf("Eval(%q) failed: %v", tt.src, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Eval(%q) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+// errorTests are expressions that fail to parse or evaluate, with the column
+// the error is reported at and a part of its message.
+var errorTests = []struct {
+	src string
+	pos int
+	msg string
+}{
+	// Parse errors.
+	{"", 1, "empty expression"},
+	{"   ", 4, "empty expression"},
+	{"1 +", 4, "found end of expression"},
+	{"1 2", 3, "unexpected number 2"},
+	{"2 $ 3", 3, `unexpected character '$'`},
+	{"x + π", 5, `unexpected character 'π'`},
+	{"1..2", 1, "invalid number 1..2"},
+	{"* 2", 1, `found "*"`},
+	{"(1 + 2", 7, `expected ")" to close "(" at column 1`},
+	{"((1 + 2) * 3", 13, `expected ")" to close "(" at column 1`},
+	{"(1 + 2))", 8, `unexpected ")"`},
+	{"()", 2, `found ")"`},
+	{"max(1 2)", 7, `expected "," or ")" to close "(" at column 4`},
+	{"max(1,)", 7, `found ")"`},
+	{"sqrt(1", 7, `expected "," or ")" to close "(" at column 5`},
+	// Evaluation errors.
+	{"1 / (2 - 2)", 3, "division by zero"},
+	{"5 % 0", 3, "modulo by zero"},
+	{"x + z", 5, `unbound variable "z"`},
+	{"2 * foo(1)", 5, `unknown function "foo", want one of abs,`},
+	{"x(2)", 1, `unknown function "x"`},
+	{"1 + pow(2)", 5, "pow takes 2 argument(s), got 1"},
+	{"max()", 1, "max takes at least 1 argument(s), got 0"},
+	{"1 + sqrt(-4)", 5, "square root of negative number -4"},
+	{"ln(0)", 1, "logarithm of non-positive number 0"},
+	{"(-8)^(1/3)", 5, "-8 ^ 0.3333333333333333 is undefined"},
+	{"10^400 - 10^400", 8, "is undefined"},
+}
+
+func TestErrors(t *testing.T) {
+	for _, tt := range errorTests {
+		e, err := expr.Parse(tt.src)
+		if err == nil {
+			_, err = e.Eval(map[string]float64{"x": 1})
+		}
+		if err == nil {
+			t.Errorf("%q: no error, want %q at column %d", tt.src, tt.msg, tt.pos)
+			continue
+		}
+		exprErr, ok := err.(*expr.Error)
+		if !ok {
+			t.Errorf("%q: error %v is a %T, want an *expr.Error", tt.src, err, err)
+			continue
+		}
+		if exprErr.Pos != tt.pos || !strings.Contains(exprErr.Msg, tt.msg) {
+			t.Errorf("%q: error %v, want %q at column %d", tt.src, err, tt.msg, tt.pos)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	nest := func(open, x, close string, n int) string {
+		return strings.Repeat(open, n) + x + strings.Repeat(close, n)
+	}
+	ok := []string{
+		nest("(", "1", ")", expr.MaxDepth),
+		nest("-", "1", "", expr.MaxDepth),
+		nest("abs(", "1", ")", expr.MaxDepth),
+		nest("2^", "1", "", expr.MaxDepth),
+		strings.Repeat("1+", expr.MaxLength/2-1) + "1",
+	}
+	for _, src := range ok {
+		if _, err := expr.Parse(src); err != nil {
+			t.Errorf("Parse(%.20q...) failed: %v", src, err)
+		}
+	}
+
+	tooDeep := []struct {
+		src string
+		pos int
+	}{
+		{nest("(", "1", ")", expr.MaxDepth+1), expr.MaxDepth + 1},
+		{nest("-", "1", "", expr.MaxDepth+1), expr.MaxDepth + 1},
+		{nest("abs(", "1", ")", expr.MaxDepth+1), 4*expr.MaxDepth + 4},
+		{nest("2^", "1", "", expr.MaxDepth+1), 2*expr.MaxDepth + 2},
+		{"1 + " + nest("(", "1", ")", expr.MaxDepth+1), expr.MaxDepth + 5},
+	}
+	for _, tt := range tooDeep {
+		_, err := expr.Parse(tt.src)
+		exprErr, ok := err.(*expr.Error)
+		if !ok || exprErr.Pos != tt.pos || !strings.Contains(exprErr.Msg, "nested deeper than") {
+			t.Errorf("Parse(%.20q...) = %v, want a nesting error at column %d", tt.src, err, tt.pos)
+		}
+	}
+
+	long := strings.Repeat("1+", expr.MaxLength/2) + "1"
+	if _, err := expr.Parse(long); err == nil || !strings.Contains(err.Error(), "longer than") {
+		t.Errorf("Parse of %d bytes = %v, want a length error", len(long), err)
+	}
+}
@@ -0,0 +1,385 @@
+// Package expr parses and evaluates infix arithmetic expressions such as
+// "(3 + 4) * sqrt(16) / 2", with variables and a few functions, so that a
+// multi-step calculation takes a single round trip.
+//
+// The grammar, from lowest to highest precedence:
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/" | "%") unary }
+//	unary   = ("+" | "-") unary | power
+//	power   = primary [ "^" unary ]
+//	primary = number | name | name "(" [ expr { "," expr } ] ")" | "(" expr ")"
+//
+// "^" is right associative and binds tighter than unary minus, so -2^2 is -4.
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxLength is the longest expression Parse accepts, in bytes.
+	MaxLength = 4096
+	// MaxDepth is how deeply Parse lets parentheses, function calls and
+	// unary operators nest.
+	MaxDepth = 100
+)
+
+// Error is a parse or evaluation error at a position in the expression.
+type Error struct {
+	// Pos is the 1-based column, in characters, the error was found at.
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Pos, e.Msg)
+}
+
+// Node is a node of a parsed expression.
+type Node interface {
+	// pos is the byte offset of the node in the expression.
+	pos() int
+}
+
+type (
+	numberNode struct {
+		off   int
+		value float64
+	}
+	varNode struct {
+		off  int
+		name string
+	}
+	unaryNode struct {
+		off int
+		op  byte
+		x   Node
+	}
+	binaryNode struct {
+		off  int
+		op   byte
+		x, y Node
+	}
+	callNode struct {
+		off  int
+		name string
+		args []Node
+	}
+)
+
+func (n *numberNode) pos() int { return n.off }
+func (n *varNode) pos() int    { return n.off }
+func (n *unaryNode) pos() int  { return n.off }
+func (n *binaryNode) pos() int { return n.off }
+func (n *callNode) pos() int   { return n.off }
+
+// Expr is a parsed expression.
+type Expr struct {
+	src  string
+	root Node
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokName
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	off  int
+	text string
+}
+
+type parser struct {
+	src   string
+	off   int
+	tok   token
+	depth int
+}
+
+// Parse parses an expression. Errors are of type *Error.
+func Parse(src string) (*Expr, error) {
+	if len(src) > MaxLength {
+		return nil, &Error{Pos: 1, Msg: fmt.Sprintf("expression longer than %d bytes", MaxLength)}
+	}
+	p := &parser{src: src}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokEOF {
+		return nil, p.errorf(p.tok.off, "empty expression")
+	}
+	root, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf(p.tok.off, "unexpected %s", p.tok.describe())
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+func (p *parser) errorf(off int, format string, args ...interface{}) *Error {
+	return &Error{Pos: column(p.src, off), Msg: fmt.Sprintf(format, args...)}
+}
+
+// column converts a byte offset in src to a 1-based character column.
+func column(src string, off int) int {
+	if off > len(src) {
+		off = len(src)
+	}
+	return utf8.RuneCountInString(src[:off]) + 1
+}
+
+func (t token) describe() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokNumber:
+		return "number " + t.text
+	case tokName:
+		return "name " + strconv.Quote(t.text)
+	default:
+		return strconv.Quote(t.text)
+	}
+}
+
+// next scans the following token into p.tok.
+func (p *parser) next() error {
+	for p.off < len(p.src) && (p.src[p.off] == ' ' || p.src[p.off] == '\t' || p.src[p.off] == '\n' || p.src[p.off] == '\r') {
+		p.off++
+	}
+	start := p.off
+	if p.off == len(p.src) {
+		p.tok = token{kind: tokEOF, off: start}
+		return nil
+	}
+
+	c := p.src[p.off]
+	switch {
+	case isDigit(c) || c == '.':
+		for p.off < len(p.src) && (isDigit(p.src[p.off]) || p.src[p.off] == '.') {
+			p.off++
+		}
+		if p.off < len(p.src) && (p.src[p.off] == 'e' || p.src[p.off] == 'E') {
+			end := p.off + 1
+			if end < len(p.src) && (p.src[end] == '+' || p.src[end] == '-') {
+				end++
+			}
+			if end < len(p.src) && isDigit(p.src[end]) {
+				for end < len(p.src) && isDigit(p.src[end]) {
+					end++
+				}
+				p.off = end
+			}
+		}
+		p.tok = token{kind: tokNumber, off: start, text: p.src[start:p.off]}
+	case c == '_' || c < utf8.RuneSelf && unicode.IsLetter(rune(c)):
+		for p.off < len(p.src) && (p.src[p.off] == '_' || isDigit(p.src[p.off]) || p.src[p.off] < utf8.RuneSelf && unicode.IsLetter(rune(p.src[p.off]))) {
+			p.off++
+		}
+		p.tok = token{kind: tokName, off: start, text: p.src[start:p.off]}
+	case strings.IndexByte("+-*/%^(),", c) >= 0:
+		p.off++
+		p.tok = token{kind: tokOp, off: start, text: p.src[start:p.off]}
+	default:
+		r, _ := utf8.DecodeRuneInString(p.src[p.off:])
+		return p.errorf(start, "unexpected character %q", r)
+	}
+	return nil
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func (p *parser) isOp(ops string) bool {
+	return p.tok.kind == tokOp && strings.Contains(ops, p.tok.text)
+}
+
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > MaxDepth {
+		return p.errorf(p.tok.off, "expression nested deeper than %d levels", MaxDepth)
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) expr() (Node, error) {
+	x, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+-") {
+		op := p.tok
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		y, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		x = &binaryNode{off: op.off, op: op.text[0], x: x, y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) term() (Node, error) {
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*/%") {
+		op := p.tok
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		y, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		x = &binaryNode{off: op.off, op: op.text[0], x: x, y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) unary() (Node, error) {
+	if !p.isOp("+-") {
+		return p.power()
+	}
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	op := p.tok
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return &unaryNode{off: op.off, op: op.text[0], x: x}, nil
+}
+
+func (p *parser) power() (Node, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp("^") {
+		return x, nil
+	}
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	op := p.tok
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	y, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return &binaryNode{off: op.off, op: '^', x: x, y: y}, nil
+}
+
+func (p *parser) primary() (Node, error) {
+	tok := p.tok
+	switch {
+	case tok.kind == tokNumber:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorf(tok.off, "invalid number %s", tok.text)
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		return &numberNode{off: tok.off, value: v}, nil
+
+	case tok.kind == tokName:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if !p.isOp("(") {
+			return &varNode{off: tok.off, name: tok.text}, nil
+		}
+		args, err := p.args()
+		if err != nil {
+			return nil, err
+		}
+		return &callNode{off: tok.off, name: tok.text, args: args}, nil
+
+	case p.isOp("("):
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isOp(")") {
+			return nil, p.errorf(p.tok.off, "expected \")\" to close \"(\" at column %d, found %s", column(p.src, tok.off), p.tok.describe())
+		}
+		return x, p.next()
+	}
+	return nil, p.errorf(tok.off, "expected a number, name or \"(\", found %s", tok.describe())
+}
+
+// args parses the parenthesized arguments of a function call.
+func (p *parser) args() ([]Node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	open := p.tok
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	var args []Node
+	if p.isOp(")") {
+		return args, p.next()
+	}
+	for {
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, x)
+		if p.isOp(")") {
+			return args, p.next()
+		}
+		if !p.isOp(",") {
+			return nil, p.errorf(p.tok.off, "expected \",\" or \")\" to close \"(\" at column %d, found %s", column(p.src, open.off), p.tok.describe())
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+}
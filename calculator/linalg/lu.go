@@ -0,0 +1,196 @@
+package linalg
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// singularTolerance is how small a pivot may be, relative to the largest
+// elements of its row and column in the matrix, before the matrix is
+// considered singular.
+const singularTolerance = 1e-12
+
+// lu is the LU decomposition with partial pivoting of a square matrix:
+// the rows of the matrix permuted by perm equal L x U, with L unit lower
+// triangular and U upper triangular, both stored in m.
+type lu struct {
+	m    *Matrix
+	perm []int
+	// sign is the sign of the permutation, for the determinant.
+	sign float64
+	// singularAt is the 1-based column whose pivot vanished, or 0.
+	singularAt int
+}
+
+func decompose(ctx context.Context, a *Matrix) (*lu, error) {
+	if err := CheckSquare(a); err != nil {
+		return nil, err
+	}
+	n := a.Rows
+	d := &lu{m: a.clone(), perm: make([]int, n), sign: 1}
+	for i := range d.perm {
+		d.perm[i] = i
+	}
+
+	// Pivots are chosen and tested as if the matrix were equilibrated, its
+	// rows and then its columns divided by their largest element, so that
+	// badly scaled matrices such as diag(1e-20, 1e20) are not mistaken for
+	// singular ones. rowScale follows the rows as they are swapped.
+	rowScale, colScale := make([]float64, n), make([]float64, n)
+	for i := 0; i < n; i++ {
+		for _, v := range a.Row(i) {
+			rowScale[i] = math.Max(rowScale[i], math.Abs(v))
+		}
+		if rowScale[i] == 0 {
+			continue
+		}
+		for j, v := range a.Row(i) {
+			colScale[j] = math.Max(colScale[j], math.Abs(v)/rowScale[i])
+		}
+	}
+	scaled := func(i, k int) float64 {
+		if rowScale[i] == 0 {
+			return 0
+		}
+		return math.Abs(d.m.Data[i*n+k]) / rowScale[i]
+	}
+
+	m := d.m
+	for k := 0; k < n; k++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		p := k
+		for i := k + 1; i < n; i++ {
+			if scaled(i, k) > scaled(p, k) {
+				p = i
+			}
+		}
+		if p != k {
+			rp, rk := m.Row(p), m.Row(k)
+			for j := range rp {
+				rp[j], rk[j] = rk[j], rp[j]
+			}
+			d.perm[p], d.perm[k] = d.perm[k], d.perm[p]
+			rowScale[p], rowScale[k] = rowScale[k], rowScale[p]
+			d.sign = -d.sign
+		}
+		if scaled(k, k) <= singularTolerance*colScale[k] && d.singularAt == 0 {
+			d.singularAt = k + 1
+		}
+		pivot := m.Data[k*n+k]
+		if pivot == 0 {
+			continue
+		}
+		for i := k + 1; i < n; i++ {
+			f := m.Data[i*n+k] / pivot
+			m.Data[i*n+k] = f
+			if f == 0 {
+				continue
+			}
+			ri, rk := m.Row(i), m.Row(k)
+			for j := k + 1; j < n; j++ {
+				ri[j] -= f * rk[j]
+			}
+		}
+	}
+	return d, nil
+}
+
+func (d *lu) singularError() error {
+	return fmt.Errorf("%w: no usable pivot in column %d, the rows are linearly dependent", ErrSingular, d.singularAt)
+}
+
+// Det returns the determinant of the square matrix a, the product of the
+// pivots of its LU decomposition. It is 0 if a pivot is, and may only be tiny
+// for a matrix that is singular up to rounding errors.
+func Det(ctx context.Context, a *Matrix) (float64, error) {
+	d, err := decompose(ctx, a)
+	if err != nil {
+		return 0, err
+	}
+	det := d.sign
+	for k := 0; k < a.Rows; k++ {
+		det *= d.m.Data[k*a.Rows+k]
+	}
+	return det, nil
+}
+
+// Solve returns X such that a x X = b, for a square and non-singular.
+// Each column of b is a right-hand side.
+func Solve(ctx context.Context, a, b *Matrix) (*Matrix, error) {
+	if err := CheckSolve(a, b); err != nil {
+		return nil, err
+	}
+	d, err := decompose(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	if d.singularAt != 0 {
+		return nil, d.singularError()
+	}
+	return d.solve(ctx, b)
+}
+
+// Inverse returns the inverse of the square, non-singular matrix a.
+func Inverse(ctx context.Context, a *Matrix) (*Matrix, error) {
+	d, err := decompose(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	if d.singularAt != 0 {
+		return nil, d.singularError()
+	}
+	id := Zeros(a.Rows, a.Rows)
+	for i := 0; i < a.Rows; i++ {
+		id.Data[i*a.Rows+i] = 1
+	}
+	return d.solve(ctx, id)
+}
+
+func (d *lu) solve(ctx context.Context, b *Matrix) (*Matrix, error) {
+	n, m := d.m.Rows, b.Cols
+	x := Zeros(n, m)
+	for i := 0; i < n; i++ {
+		copy(x.Row(i), b.Row(d.perm[i]))
+	}
+	// Forward substitution with L, then back substitution with U.
+	for i := 0; i < n; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		xi := x.Row(i)
+		for k := 0; k < i; k++ {
+			f := d.m.Data[i*n+k]
+			if f == 0 {
+				continue
+			}
+			xk := x.Row(k)
+			for j := range xi {
+				xi[j] -= f * xk[j]
+			}
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		xi := x.Row(i)
+		for k := i + 1; k < n; k++ {
+			f := d.m.Data[i*n+k]
+			if f == 0 {
+				continue
+			}
+			xk := x.Row(k)
+			for j := range xi {
+				xi[j] -= f * xk[j]
+			}
+		}
+		pivot := d.m.Data[i*n+i]
+		for j := range xi {
+			xi[j] /= pivot
+		}
+	}
+	return x, nil
+}
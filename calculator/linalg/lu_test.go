@@ -0,0 +1,136 @@
+package linalg_test
+
+import (
+	"context"
+	"errors"
+	"grpc-go-course/calculator/linalg"
+	"math"
+	"strings"
+	"testing"
+)
+
+func square(rows ...[]float64) *linalg.Matrix {
+	m := linalg.Zeros(len(rows), len(rows))
+	for i, row := range rows {
+		copy(m.Row(i), row)
+	}
+	return m
+}
+
+// near reports whether got is within tolerance of want, relative to want.
+func near(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance*math.Abs(want)
+}
+
+var luTests = []struct {
+	name string
+	a    *linalg.Matrix
+	// det is the exact determinant, and singular whether a is singular.
+	det      float64
+	singular bool
+}{
+	{"1x1", square([]float64{-3}), -3, false},
+	{"identity", square([]float64{1, 0, 0}, []float64{0, 1, 0}, []float64{0, 0, 1}), 1, false},
+	{"permutation", square([]float64{0, 1, 0}, []float64{0, 0, 1}, []float64{1, 0, 0}), 1, false},
+	{"swap", square([]float64{0, 1}, []float64{1, 0}), -1, false},
+	{"3x3", square([]float64{2, -1, 0}, []float64{-1, 2, -1}, []float64{0, -1, 2}), 4, false},
+	{"4x4", square([]float64{4, 3, 2, 1}, []float64{3, 4, 3, 2}, []float64{2, 3, 4, 3}, []float64{1, 2, 3, 4}), 20, false},
+
+	// Badly scaled but well conditioned matrices.
+	{"tiny and huge rows", square([]float64{1e-20, 0}, []float64{0, 1e20}), 1, false},
+	{"tiny row", square([]float64{1, 0}, []float64{0, 1e-13}), 1e-13, false},
+	{"tiny column", square([]float64{1e-20, 1}, []float64{1e-20, 2}), 1e-20, false},
+	{"scaled rows", square([]float64{1e-30, 2e-30}, []float64{3e30, 4e30}), -2, false},
+	{"tiny matrix", square([]float64{1e-150, 0}, []float64{0, 1e-150}), 1e-300, false},
+
+	// Near-singular matrices, which are not singular.
+	{"near singular", square([]float64{1, 1}, []float64{1, 1 + 1e-10}), 1e-10, false},
+	{"near singular 3x3", square([]float64{1, 2, 3}, []float64{4, 5, 6}, []float64{7, 8, 9 + 1e-6}), -3e-6, false},
+
+	// Singular matrices, exactly or up to rounding errors.
+	{"zeros", square([]float64{0, 0}, []float64{0, 0}), 0, true},
+	{"zero row", square([]float64{1, 2}, []float64{0, 0}), 0, true},
+	{"zero column", square([]float64{1, 0}, []float64{2, 0}), 0, true},
+	{"dependent rows", square([]float64{1, 2}, []float64{2, 4}), 0, true},
+	{"dependent rows 3x3", square([]float64{1, 2, 3}, []float64{4, 5, 6}, []float64{7, 8, 9}), 0, true},
+	{"scaled dependent rows", square([]float64{1e-20, 2e-20}, []float64{1e20, 2e20}), 0, true},
+	{"rank 2 of 4", square([]float64{1, 2, 3, 4}, []float64{2, 4, 6, 8.000000000000001}, []float64{1, 0, 1, 0}, []float64{2, 2, 4, 4}), 0, true},
+}
+
+func TestDet(t *testing.T) {
+	for _, tt := range luTests {
+		got, err := linalg.Det(context.Background(), tt.a)
+		if err != nil {
+			t.Errorf("%s: Det failed: %v", tt.name, err)
+			continue
+		}
+		switch {
+		case tt.singular:
+			if math.Abs(got) > 1e-12 {
+				t.Errorf("%s: Det = %v, want about 0", tt.name, got)
+			}
+		case !near(got, tt.det, 1e-6):
+			t.Errorf("%s: Det = %v, want %v", tt.name, got, tt.det)
+		}
+	}
+}
+
+// checkSolution checks that a x X = B up to rounding errors, componentwise:
+// each element of a x X - B must be small next to the corresponding element
+// of |a| x |X|, since badly scaled matrices have solutions of any scale.
+func checkSolution(t *testing.T, name string, a, x, b *linalg.Matrix) {
+	t.Helper()
+	n := a.Rows
+	for i := 0; i < n; i++ {
+		for j := 0; j < b.Cols; j++ {
+			sum, abs := -b.Data[i*b.Cols+j], 0.0
+			for k := 0; k < n; k++ {
+				sum += a.Data[i*n+k] * x.Data[k*x.Cols+j]
+				abs += math.Abs(a.Data[i*n+k] * x.Data[k*x.Cols+j])
+			}
+			if math.Abs(sum) > 1e-9*abs {
+				t.Errorf("%s: element (%d, %d) of the product is off by %v", name, i+1, j+1, sum)
+			}
+		}
+	}
+}
+
+func TestSolveInverse(t *testing.T) {
+	for _, tt := range luTests {
+		n := tt.a.Rows
+		b := linalg.Zeros(n, 1)
+		for i := range b.Data {
+			b.Data[i] = float64(i + 1)
+		}
+		id := linalg.Zeros(n, n)
+		for i := 0; i < n; i++ {
+			id.Data[i*n+i] = 1
+		}
+
+		x, err := linalg.Solve(context.Background(), tt.a, b)
+		inv, invErr := linalg.Inverse(context.Background(), tt.a)
+		if tt.singular {
+			if !errors.Is(err, linalg.ErrSingular) || !errors.Is(invErr, linalg.ErrSingular) {
+				t.Errorf("%s: Solve and Inverse failed with %v and %v, want %v", tt.name, err, invErr, linalg.ErrSingular)
+			}
+			continue
+		}
+		if err != nil || invErr != nil {
+			t.Errorf("%s: Solve and Inverse failed with %v and %v", tt.name, err, invErr)
+			continue
+		}
+		checkSolution(t, tt.name+": Solve", tt.a, x, b)
+		checkSolution(t, tt.name+": Inverse", tt.a, inv, id)
+	}
+}
+
+func TestSingularColumn(t *testing.T) {
+	a := square([]float64{1, 2, 3}, []float64{2, 4, 7}, []float64{3, 6, 1})
+	_, err := linalg.Inverse(context.Background(), a)
+	if !errors.Is(err, linalg.ErrSingular) {
+		t.Fatalf("Inverse = %v, want %v", err, linalg.ErrSingular)
+	}
+	if want := "no usable pivot in column 2"; !strings.Contains(err.Error(), want) {
+		t.Errorf("Inverse = %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,150 @@
+// Package linalg implements dense matrix operations on float64 matrices
+// stored in row-major order.
+package linalg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// MaxElements bounds the number of elements of a matrix, and MaxDimension
+// its number of rows and columns.
+const (
+	MaxElements  = 1 << 22
+	MaxDimension = 1 << 12
+)
+
+var (
+	// ErrDimensions is returned for operands of incompatible or invalid
+	// dimensions.
+	ErrDimensions = errors.New("dimension mismatch")
+	// ErrSingular is returned when inverting a singular matrix or solving a
+	// system with one.
+	ErrSingular = errors.New("matrix is singular")
+)
+
+// Matrix is a dense matrix. Element (i, j) is Data[i*Cols+j].
+type Matrix struct {
+	Rows, Cols int
+	Data       []float64
+}
+
+// New returns a rows x cols matrix with the given elements in row-major
+// order, checking that they match the dimensions and are finite.
+func New(rows, cols int, data []float64) (*Matrix, error) {
+	if err := CheckDimensions(rows, cols); err != nil {
+		return nil, err
+	}
+	if len(data) != rows*cols {
+		return nil, fmt.Errorf("%w: a %dx%d matrix has %d elements, got %d", ErrDimensions, rows, cols, rows*cols, len(data))
+	}
+	for i, v := range data {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("element (%d, %d) is not finite: %v", i/cols+1, i%cols+1, v)
+		}
+	}
+	return &Matrix{Rows: rows, Cols: cols, Data: data}, nil
+}
+
+// CheckDimensions checks that a rows x cols matrix is within the limits.
+func CheckDimensions(rows, cols int) error {
+	if rows < 1 || cols < 1 {
+		return fmt.Errorf("%w: a matrix needs at least one row and column, got %dx%d", ErrDimensions, rows, cols)
+	}
+	if rows > MaxDimension || cols > MaxDimension || rows*cols > MaxElements {
+		return fmt.Errorf("%w: %dx%d exceeds the limits of %d rows or columns and %d elements", ErrDimensions, rows, cols, MaxDimension, MaxElements)
+	}
+	return nil
+}
+
+// Zeros returns a rows x cols matrix of zeros.
+func Zeros(rows, cols int) *Matrix {
+	return &Matrix{Rows: rows, Cols: cols, Data: make([]float64, rows*cols)}
+}
+
+// Row returns row i, sharing the matrix's storage.
+func (m *Matrix) Row(i int) []float64 {
+	return m.Data[i*m.Cols : (i+1)*m.Cols]
+}
+
+func (m *Matrix) String() string {
+	return fmt.Sprintf("%dx%d", m.Rows, m.Cols)
+}
+
+func (m *Matrix) clone() *Matrix {
+	return &Matrix{Rows: m.Rows, Cols: m.Cols, Data: append([]float64(nil), m.Data...)}
+}
+
+// Transpose returns the transpose of m.
+func Transpose(m *Matrix) *Matrix {
+	t := Zeros(m.Cols, m.Rows)
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			t.Data[j*t.Cols+i] = m.Data[i*m.Cols+j]
+		}
+	}
+	return t
+}
+
+// CheckMul checks that a and b can be multiplied. Only their dimensions are
+// used, so that they can be checked before the elements are known.
+func CheckMul(a, b *Matrix) error {
+	if a.Cols != b.Rows {
+		return fmt.Errorf("%w: cannot multiply %v by %v, the columns of the first must match the rows of the second", ErrDimensions, a, b)
+	}
+	return CheckDimensions(a.Rows, b.Cols)
+}
+
+// CheckSquare checks that a is square.
+func CheckSquare(a *Matrix) error {
+	if a.Rows != a.Cols {
+		return fmt.Errorf("%w: %v matrix is not square", ErrDimensions, a)
+	}
+	return nil
+}
+
+// CheckSolve checks that the system a x X = b can be solved, not counting
+// a being singular.
+func CheckSolve(a, b *Matrix) error {
+	if err := CheckSquare(a); err != nil {
+		return err
+	}
+	if b.Rows != a.Rows {
+		return fmt.Errorf("%w: cannot solve %v system with %v right-hand side, they must have as many rows", ErrDimensions, a, b)
+	}
+	return nil
+}
+
+// Mul returns the product a x b.
+func Mul(ctx context.Context, a, b *Matrix) (*Matrix, error) {
+	if err := CheckMul(a, b); err != nil {
+		return nil, err
+	}
+	c := Zeros(a.Rows, b.Cols)
+	for i := 0; i < a.Rows; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		MulRow(c.Row(i), a.Row(i), b)
+	}
+	return c, nil
+}
+
+// MulRow sets dst to the product of the row vector row by b. len(row) must be
+// b.Rows and len(dst) b.Cols.
+func MulRow(dst, row []float64, b *Matrix) {
+	for j := range dst {
+		dst[j] = 0
+	}
+	for k, v := range row {
+		if v == 0 {
+			continue
+		}
+		bk := b.Row(k)
+		for j := range dst {
+			dst[j] += v * bk[j]
+		}
+	}
+}
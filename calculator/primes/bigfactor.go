@@ -0,0 +1,106 @@
+package primes
+
+import (
+	"context"
+	"math/big"
+)
+
+// millerRabinRounds is the number of random bases ProbablyPrime tests big
+// numbers with, on top of a Baillie-PSW test.
+const millerRabinRounds = 20
+
+// FactorBig is Factor for numbers of any size. Numbers that fit in 64 bits
+// are handed to Factor; larger ones are split with Pollard's rho on big
+// integers, which can take very long for products of two large primes.
+// Numbers below 1 fail with ErrNotPositive.
+func FactorBig(ctx context.Context, n *big.Int, fn func(p *big.Int) error) error {
+	if n.Sign() <= 0 {
+		return ErrNotPositive
+	}
+	if n.IsUint64() {
+		return Factor(ctx, n.Uint64(), func(p uint64) error {
+			return fn(new(big.Int).SetUint64(p))
+		})
+	}
+
+	n = new(big.Int).Set(n)
+	m := new(big.Int)
+	for _, p := range smallPrimes {
+		bp := new(big.Int).SetUint64(p)
+		for m.Mod(n, bp).Sign() == 0 {
+			if err := fn(bp); err != nil {
+				return err
+			}
+			n.Quo(n, bp)
+		}
+	}
+	return factorBigLarge(ctx, n, fn)
+}
+
+// factorBigLarge factors n, which has no prime factors below
+// smallPrimesLimit.
+func factorBigLarge(ctx context.Context, n *big.Int, fn func(p *big.Int) error) error {
+	if n.Cmp(big.NewInt(1)) == 0 {
+		return nil
+	}
+	if n.IsUint64() {
+		return factorLarge(ctx, n.Uint64(), func(p uint64) error {
+			return fn(new(big.Int).SetUint64(p))
+		})
+	}
+	if n.ProbablyPrime(millerRabinRounds) {
+		return fn(n)
+	}
+	d, err := pollardRhoBig(ctx, n)
+	if err != nil {
+		return err
+	}
+	if err := factorBigLarge(ctx, d, fn); err != nil {
+		return err
+	}
+	return factorBigLarge(ctx, new(big.Int).Quo(n, d), fn)
+}
+
+// pollardRhoBig is pollardRho on big integers.
+func pollardRhoBig(ctx context.Context, n *big.Int) (*big.Int, error) {
+	one := big.NewInt(1)
+	for c := int64(1); ; c++ {
+		bc := big.NewInt(c)
+		f := func(x *big.Int) {
+			x.Mul(x, x)
+			x.Add(x, bc)
+			x.Mod(x, n)
+		}
+
+		y, x, ys := big.NewInt(2), new(big.Int), new(big.Int)
+		q, d, diff := big.NewInt(1), big.NewInt(1), new(big.Int)
+		for r := 1; d.Cmp(one) == 0; r *= 2 {
+			x.Set(y)
+			for i := 0; i < r; i++ {
+				f(y)
+			}
+			for k := 0; k < r && d.Cmp(one) == 0; k += rhoBatch {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				ys.Set(y)
+				for i := 0; i < rhoBatch && i < r-k; i++ {
+					f(y)
+					q.Mul(q, diff.Abs(diff.Sub(x, y)))
+					q.Mod(q, n)
+				}
+				d.GCD(nil, nil, q, n)
+			}
+		}
+		if d.Cmp(n) == 0 {
+			// The batch overshot the cycle; retrace it one step at a time.
+			for d.SetInt64(1); d.Cmp(one) == 0; {
+				f(ys)
+				d.GCD(nil, nil, diff.Abs(diff.Sub(x, ys)), n)
+			}
+		}
+		if d.Cmp(n) != 0 {
+			return d, nil
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Package primes tests and factors 64-bit integers, fast enough for inputs
+// near 2^63: primality is decided with a deterministic Miller-Rabin test and
+// composites are split with Pollard's rho.
+package primes
+
+import (
+	"context"
+	"errors"
+	"math/bits"
+)
+
+// ErrNotPositive is returned when asked to factor zero or a negative number,
+// which have no prime factorization.
+var ErrNotPositive = errors.New("only positive integers have a prime factorization")
+
+// smallPrimesLimit bounds the primes Factor divides out by trial division
+// before resorting to Pollard's rho.
+const smallPrimesLimit = 1000
+
+// smallPrimes are the primes below smallPrimesLimit.
+var smallPrimes = sieve(smallPrimesLimit)
+
+// sieve returns the primes below n.
+func sieve(n int) []uint64 {
+	composite := make([]bool, n)
+	var ps []uint64
+	for i := 2; i < n; i++ {
+		if composite[i] {
+			continue
+		}
+		ps = append(ps, uint64(i))
+		for j := i * i; j < n; j += i {
+			composite[j] = true
+		}
+	}
+	return ps
+}
+
+// Factor calls fn with each prime factor of n, repeated according to its
+// multiplicity, as soon as it is found. Small factors come first in
+// ascending order; larger ones come in no particular order. Factor stops and
+// returns the error if fn fails, or ctx.Err() if ctx is done. 1 has no
+// prime factors, and 0 fails with ErrNotPositive.
+func Factor(ctx context.Context, n uint64, fn func(p uint64) error) error {
+	if n == 0 {
+		return ErrNotPositive
+	}
+	for _, p := range smallPrimes {
+		if p*p > n {
+			break
+		}
+		for n%p == 0 {
+			if err := fn(p); err != nil {
+				return err
+			}
+			n /= p
+		}
+	}
+	if n == 1 {
+		return nil
+	}
+	return factorLarge(ctx, n, fn)
+}
+
+// factorLarge factors n, which has no prime factors below smallPrimesLimit.
+func factorLarge(ctx context.Context, n uint64, fn func(p uint64) error) error {
+	if IsPrime(n) {
+		return fn(n)
+	}
+	d, err := pollardRho(ctx, n)
+	if err != nil {
+		return err
+	}
+	if err := factorLarge(ctx, d, fn); err != nil {
+		return err
+	}
+	return factorLarge(ctx, n/d, fn)
+}
+
+// rhoBatch is how many steps pollardRho multiplies together before taking a
+// gcd with n, and between checks for cancellation.
+const rhoBatch = 128
+
+// pollardRho returns a non-trivial divisor of the odd composite n, using
+// Brent's variant of Pollard's rho with the polynomials x^2 + c.
+func pollardRho(ctx context.Context, n uint64) (uint64, error) {
+	for c := uint64(1); ; c++ {
+		f := func(x uint64) uint64 { return addMod(mulMod(x, x, n), c, n) }
+
+		y, x, ys := uint64(2), uint64(0), uint64(0)
+		q, d := uint64(1), uint64(1)
+		for r := uint64(1); d == 1; r *= 2 {
+			x = y
+			for i := uint64(0); i < r; i++ {
+				y = f(y)
+			}
+			for k := uint64(0); k < r && d == 1; k += rhoBatch {
+				if err := ctx.Err(); err != nil {
+					return 0, err
+				}
+				ys = y
+				for i := uint64(0); i < rhoBatch && i < r-k; i++ {
+					y = f(y)
+					q = mulMod(q, absDiff(x, y), n)
+				}
+				d = gcd(q, n)
+			}
+		}
+		if d == n {
+			// The batch overshot the cycle; retrace it one step at a time.
+			for d = 1; d == 1; {
+				ys = f(ys)
+				d = gcd(absDiff(x, ys), n)
+			}
+		}
+		if d != n {
+			return d, nil
+		}
+		// This polynomial failed, try the next one.
+	}
+}
+
+func mulMod(a, b, m uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return bits.Rem64(hi, lo, m)
+}
+
+func addMod(a, b, m uint64) uint64 {
+	sum, carry := bits.Add64(a, b, 0)
+	if carry != 0 || sum >= m {
+		sum -= m
+	}
+	return sum
+}
+
+func absDiff(a, b uint64) uint64 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
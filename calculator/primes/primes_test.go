@@ -0,0 +1,281 @@
+package primes_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"grpc-go-course/calculator/primes"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestIsPrime(t *testing.T) {
+	tests := []struct {
+		n    uint64
+		want bool
+	}{
+		{0, false},
+		{1, false},
+		{2, true},
+		{3, true},
+		{4, false},
+		{37, true},
+		{41, true},
+		{1000000007, true},
+		// The largest primes below 2^63 and 2^64.
+		{9223372036854775783, true},
+		{18446744073709551557, true},
+		{18446744073709551615, false},
+		// Squares and products of two large primes.
+		{4294967291 * 4294967291, false},
+		{3037000493 * 3037000453, false},
+		// Carmichael numbers, Fermat pseudoprimes to every coprime base.
+		{561, false},
+		{1105, false},
+		{1729, false},
+		{2465, false},
+		{2821, false},
+		{6601, false},
+		{8911, false},
+		{9999109081, false},
+		// The smallest strong pseudoprimes to the bases 2, 2-3, 2-5, 2-7,
+		// 2-11, 2-13, 2-17 and 2-23.
+		{2047, false},
+		{1373653, false},
+		{25326001, false},
+		{3215031751, false},
+		{2152302898747, false},
+		{3474749660383, false},
+		{341550071728321, false},
+		{3825123056546413051, false},
+	}
+	for _, tt := range tests {
+		if got := primes.IsPrime(tt.n); got != tt.want {
+			t.Errorf("IsPrime(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestIsPrimeSmall(t *testing.T) {
+	for n := uint64(0); n < 100000; n++ {
+		want := new(big.Int).SetUint64(n).ProbablyPrime(0)
+		if got := primes.IsPrime(n); got != want {
+			t.Fatalf("IsPrime(%d) = %v, want %v", n, got, want)
+		}
+	}
+}
+
+// factorTests are numbers to factor, with their factors multiplied back.
+var factorTests = []uint64{
+	1,
+	2,
+	120,
+	1 << 63,
+	999983 * 999983,
+	600851475143,
+	9223372036854775783,
+	3037000493 * 3037000453,
+	4294967291 * 4294967279,
+	18446744073709551615,
+	18446744073709551557,
+}
+
+func TestFactor(t *testing.T) {
+	for _, n := range factorTests {
+		product := uint64(1)
+		err := primes.Factor(context.Background(), n, func(p uint64) error {
+			if !primes.IsPrime(p) {
+				t.Errorf("Factor(%d) found %d, which is not prime", n, p)
+			}
+			product *= p
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Factor(%d) failed: %v", n, err)
+			continue
+		}
+		if product != n {
+			t.Errorf("the factors of %d multiply to %d", n, product)
+		}
+	}
+}
+
+func TestFactorBig(t *testing.T) {
+	ns := []string{
+		"18446744073709551617",            // 2^64 + 1
+		"4951760154835678088235319297",    // (2^61 - 1) * (2^31 - 1)
+		"2475880078570760549798248447",    // 2^91 - 1
+		"1267650600228229401496703205376", // 2^100
+	}
+	for _, n := range factorTests {
+		ns = append(ns, new(big.Int).SetUint64(n).String())
+	}
+	for _, s := range ns {
+		n, _ := new(big.Int).SetString(s, 10)
+		product := big.NewInt(1)
+		err := primes.FactorBig(context.Background(), n, func(p *big.Int) error {
+			if !p.ProbablyPrime(20) {
+				t.Errorf("FactorBig(%v) found %v, which is not prime", n, p)
+			}
+			product.Mul(product, p)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("FactorBig(%v) failed: %v", n, err)
+			continue
+		}
+		if product.Cmp(n) != 0 {
+			t.Errorf("the factors of %v multiply to %v", n, product)
+		}
+	}
+}
+
+func TestFactorNotPositive(t *testing.T) {
+	err := primes.Factor(context.Background(), 0, func(p uint64) error {
+		t.Errorf("Factor(0) found %d", p)
+		return nil
+	})
+	if err != primes.ErrNotPositive {
+		t.Errorf("Factor(0) returned %v, want %v", err, primes.ErrNotPositive)
+	}
+	for _, n := range []int64{0, -1, -12} {
+		err := primes.FactorBig(context.Background(), big.NewInt(n), func(p *big.Int) error {
+			t.Errorf("FactorBig(%d) found %v", n, p)
+			return nil
+		})
+		if err != primes.ErrNotPositive {
+			t.Errorf("FactorBig(%d) returned %v, want %v", n, err, primes.ErrNotPositive)
+		}
+	}
+}
+
+func TestFactorCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := primes.Factor(ctx, 3037000493*3037000453, func(uint64) error { return nil })
+	if err != context.Canceled {
+		t.Errorf("Factor with a canceled context returned %v, want %v", err, context.Canceled)
+	}
+}
+
+// segmentSize is the number of numbers Range sieves at once.
+const segmentSize = 1 << 16
+
+func TestRange(t *testing.T) {
+	tests := []struct {
+		start, end uint64
+	}{
+		{0, 0},
+		{0, 1},
+		{0, 2},
+		{1, 3},
+		{2, 2},
+		{0, 100},
+		{4, 4},
+		{90, 96},
+		{97, 97},
+		// Segment boundaries.
+		{0, segmentSize - 1},
+		{0, segmentSize},
+		{0, segmentSize + 1},
+		{2, 3*segmentSize + 7},
+		{segmentSize - 10, segmentSize + 10},
+		{1000003, 1000003 + 2*segmentSize},
+		// Squares of primes, the first multiples sieved out.
+		{4294967291*4294967291 - 1000, 4294967291*4294967291 + 1000},
+		// Around maxSievePrime^2, above which the survivors of the sieve
+		// are checked with IsPrime.
+		{1<<44 - 1000, 1<<44 + 1000},
+		// Near 2^64, where the multiples of the sieving primes overflow.
+		{math.MaxUint64 - 1000, math.MaxUint64},
+		{math.MaxUint64 - segmentSize - 100, math.MaxUint64},
+		{math.MaxUint64 - 58, math.MaxUint64 - 58},
+		{math.MaxUint64, math.MaxUint64},
+		// Empty ranges.
+		{10, 9},
+		{math.MaxUint64, 0},
+	}
+	for _, tt := range tests {
+		var got []uint64
+		err := primes.Range(context.Background(), tt.start, tt.end, func(p uint64) error {
+			got = append(got, p)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Range(%d, %d) failed: %v", tt.start, tt.end, err)
+			continue
+		}
+		var want []uint64
+		for n := tt.start; n <= tt.end; n++ {
+			if primes.IsPrime(n) {
+				want = append(want, n)
+			}
+			if n == math.MaxUint64 {
+				break
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Range(%d, %d) found %d primes, want %d: %v", tt.start, tt.end, len(got), len(want), firstDiff(got, want))
+		}
+	}
+}
+
+// firstDiff describes the first difference between the primes got and want.
+func firstDiff(got, want []uint64) string {
+	for i := 0; i < len(got) && i < len(want); i++ {
+		if got[i] != want[i] {
+			return fmt.Sprintf("prime %d is %d, want %d", i, got[i], want[i])
+		}
+	}
+	if len(got) > len(want) {
+		return fmt.Sprintf("%d is not prime", got[len(want)])
+	}
+	if len(got) < len(want) {
+		return fmt.Sprintf("%d is missing", want[len(got)])
+	}
+	return "same primes"
+}
+
+func TestRangeStops(t *testing.T) {
+	stop := errors.New("stop")
+	var got []uint64
+	err := primes.Range(context.Background(), 0, 1000, func(p uint64) error {
+		got = append(got, p)
+		if len(got) == 3 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || !reflect.DeepEqual(got, []uint64{2, 3, 5}) {
+		t.Errorf("Range stopped after %v with %v, want [2 3 5] and %v", got, err, stop)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = primes.Range(ctx, 0, 1000, func(p uint64) error {
+		t.Errorf("Range with a canceled context found %d", p)
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("Range with a canceled context returned %v, want %v", err, context.Canceled)
+	}
+}
+
+func BenchmarkFactor(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		n    uint64
+	}{
+		{"prime-near-2^63", 9223372036854775783},
+		{"semiprime-62-bit", 2147483647 * 2147483629},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				primes.Factor(context.Background(), bm.n, func(uint64) error { return nil })
+			}
+		})
+	}
+}
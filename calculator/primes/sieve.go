@@ -0,0 +1,97 @@
+package primes
+
+import (
+	"context"
+	"math"
+)
+
+const (
+	// segmentSize is how many numbers Range sieves at once.
+	segmentSize = 1 << 16
+	// maxSievePrime bounds the primes Range sieves with, and so its memory.
+	// Numbers above maxSievePrime^2 that survive the sieve are checked with
+	// IsPrime.
+	maxSievePrime = 1 << 22
+)
+
+// Range calls fn with each prime between start and end inclusive, in
+// ascending order, using a segmented sieve of Eratosthenes. Its memory use
+// doesn't depend on the size of the range. Range stops and returns the error
+// if fn fails, or ctx.Err() if ctx is done.
+func Range(ctx context.Context, start, end uint64, fn func(p uint64) error) error {
+	if start < 2 {
+		start = 2
+	}
+	if start > end {
+		return nil
+	}
+
+	limit := isqrt(end)
+	verify := false
+	if limit > maxSievePrime {
+		limit, verify = maxSievePrime, true
+	}
+	base := sieve(int(limit) + 1)
+
+	composite := make([]bool, segmentSize)
+	for lo := start; ; lo += segmentSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hi := end
+		if end-lo >= segmentSize {
+			hi = lo + segmentSize - 1
+		}
+
+		seg := composite[:hi-lo+1]
+		for i := range seg {
+			seg[i] = false
+		}
+		for _, p := range base {
+			if p*p > hi {
+				break
+			}
+			m := lo / p * p
+			if m < lo {
+				if m > math.MaxUint64-p {
+					continue
+				}
+				m += p
+			}
+			if m < p*p {
+				m = p * p
+			}
+			for ; m <= hi; m += p {
+				seg[m-lo] = true
+				if hi-m < p {
+					break
+				}
+			}
+		}
+
+		for i, c := range seg {
+			n := lo + uint64(i)
+			if c || verify && !IsPrime(n) {
+				continue
+			}
+			if err := fn(n); err != nil {
+				return err
+			}
+		}
+		if hi == end {
+			return nil
+		}
+	}
+}
+
+// isqrt returns the integer square root of n.
+func isqrt(n uint64) uint64 {
+	r := uint64(math.Sqrt(float64(n)))
+	for r*r > n {
+		r--
+	}
+	for r < math.MaxUint32 && (r+1)*(r+1) <= n {
+		r++
+	}
+	return r
+}
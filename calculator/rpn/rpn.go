@@ -0,0 +1,195 @@
+// Package rpn implements a reverse Polish notation calculator: a stack of
+// numbers that operations pop their operands from and push their result to,
+// and named registers.
+package rpn
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+)
+
+// Limits of a Machine, so that a client can't exhaust the memory of the
+// server.
+const (
+	MaxDepth     = 1000
+	MaxRegisters = 100
+)
+
+// Op is an operation on the stack.
+type Op int
+
+const (
+	Add Op = iota + 1
+	Subtract
+	Multiply
+	Divide
+	Power
+	Negate
+	Sqrt
+	// Dup pushes a copy of the top of the stack, Swap exchanges the two
+	// numbers on top, Clear empties the stack.
+	Dup
+	Swap
+	Clear
+)
+
+var opNames = map[Op]string{
+	Add:      "add",
+	Subtract: "subtract",
+	Multiply: "multiply",
+	Divide:   "divide",
+	Power:    "power",
+	Negate:   "negate",
+	Sqrt:     "sqrt",
+	Dup:      "dup",
+	Swap:     "swap",
+	Clear:    "clear",
+}
+
+func (op Op) String() string {
+	if name, ok := opNames[op]; ok {
+		return name
+	}
+	return fmt.Sprintf("Op(%d)", int(op))
+}
+
+var (
+	// ErrUnderflow is returned for operations needing more numbers than the
+	// stack holds.
+	ErrUnderflow = errors.New("stack underflow")
+	// ErrOverflow is returned for pushing onto a full stack.
+	ErrOverflow = errors.New("stack overflow")
+)
+
+// registerName is what register names look like.
+var registerName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,31}$`)
+
+// Machine is the state of a calculator. The zero Machine is empty and ready
+// to use. Methods failing leave the Machine unchanged.
+type Machine struct {
+	// Stack holds the numbers, the top of the stack last.
+	Stack     []float64
+	Registers map[string]float64
+}
+
+// Push pushes v.
+func (m *Machine) Push(v float64) error {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Errorf("cannot push %v, numbers must be finite", v)
+	}
+	if len(m.Stack) >= MaxDepth {
+		return fmt.Errorf("%w: the stack holds at most %d numbers", ErrOverflow, MaxDepth)
+	}
+	m.Stack = append(m.Stack, v)
+	return nil
+}
+
+// Pop removes the top of the stack.
+func (m *Machine) Pop() error {
+	if err := m.need("pop", 1); err != nil {
+		return err
+	}
+	m.Stack = m.Stack[:len(m.Stack)-1]
+	return nil
+}
+
+// Apply applies op to the stack.
+func (m *Machine) Apply(op Op) error {
+	n := len(m.Stack)
+	switch op {
+	case Clear:
+		m.Stack = m.Stack[:0]
+		return nil
+	case Dup:
+		if err := m.need(op.String(), 1); err != nil {
+			return err
+		}
+		return m.Push(m.Stack[n-1])
+	case Swap:
+		if err := m.need(op.String(), 2); err != nil {
+			return err
+		}
+		m.Stack[n-2], m.Stack[n-1] = m.Stack[n-1], m.Stack[n-2]
+		return nil
+	case Negate, Sqrt:
+		if err := m.need(op.String(), 1); err != nil {
+			return err
+		}
+		x := m.Stack[n-1]
+		if op == Negate {
+			m.Stack[n-1] = -x
+			return nil
+		}
+		if x < 0 {
+			return fmt.Errorf("cannot take the square root of %v", x)
+		}
+		m.Stack[n-1] = math.Sqrt(x)
+		return nil
+	case Add, Subtract, Multiply, Divide, Power:
+		if err := m.need(op.String(), 2); err != nil {
+			return err
+		}
+		// a is below b, so that "3 2 subtract" is 3 - 2.
+		a, b := m.Stack[n-2], m.Stack[n-1]
+		var r float64
+		switch op {
+		case Add:
+			r = a + b
+		case Subtract:
+			r = a - b
+		case Multiply:
+			r = a * b
+		case Divide:
+			if b == 0 {
+				return fmt.Errorf("cannot divide %v by zero", a)
+			}
+			r = a / b
+		case Power:
+			r = math.Pow(a, b)
+		}
+		if math.IsNaN(r) || math.IsInf(r, 0) {
+			return fmt.Errorf("%s of %v and %v is not a finite number", op, a, b)
+		}
+		m.Stack = append(m.Stack[:n-2], r)
+		return nil
+	default:
+		return fmt.Errorf("unknown operation %v", op)
+	}
+}
+
+// Store copies the top of the stack to the named register, leaving it on the
+// stack.
+func (m *Machine) Store(name string) error {
+	if !registerName.MatchString(name) {
+		return fmt.Errorf("invalid register name %q, want a letter or underscore followed by at most 31 letters, digits or underscores", name)
+	}
+	if err := m.need("store", 1); err != nil {
+		return err
+	}
+	if _, ok := m.Registers[name]; !ok && len(m.Registers) >= MaxRegisters {
+		return fmt.Errorf("cannot store to %q, there are at most %d registers", name, MaxRegisters)
+	}
+	if m.Registers == nil {
+		m.Registers = make(map[string]float64)
+	}
+	m.Registers[name] = m.Stack[len(m.Stack)-1]
+	return nil
+}
+
+// Recall pushes the value of the named register.
+func (m *Machine) Recall(name string) error {
+	v, ok := m.Registers[name]
+	if !ok {
+		return fmt.Errorf("register %q is empty", name)
+	}
+	return m.Push(v)
+}
+
+func (m *Machine) need(what string, n int) error {
+	if len(m.Stack) < n {
+		return fmt.Errorf("%w: %s needs %d numbers, the stack holds %d", ErrUnderflow, what, n, len(m.Stack))
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package rpn_test
+
+import (
+	"errors"
+	"fmt"
+	"grpc-go-course/calculator/rpn"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		stack []float64
+		op    rpn.Op
+		want  []float64
+		// err is a part of the error, if the operation fails.
+		err string
+	}{
+		{[]float64{1, 3, 2}, rpn.Add, []float64{1, 5}, ""},
+		{[]float64{3, 2}, rpn.Subtract, []float64{1}, ""},
+		{[]float64{3, 2}, rpn.Multiply, []float64{6}, ""},
+		{[]float64{3, 2}, rpn.Divide, []float64{1.5}, ""},
+		{[]float64{2, 10}, rpn.Power, []float64{1024}, ""},
+		{[]float64{4, 0.5}, rpn.Power, []float64{2}, ""},
+		{[]float64{1, 2}, rpn.Negate, []float64{1, -2}, ""},
+		{[]float64{16}, rpn.Sqrt, []float64{4}, ""},
+		{[]float64{1, 2}, rpn.Dup, []float64{1, 2, 2}, ""},
+		{[]float64{1, 2, 3}, rpn.Swap, []float64{1, 3, 2}, ""},
+		{[]float64{1, 2, 3}, rpn.Clear, []float64{}, ""},
+		{nil, rpn.Clear, nil, ""},
+
+		{[]float64{1}, rpn.Add, nil, "stack underflow: add needs 2 numbers, the stack holds 1"},
+		{nil, rpn.Negate, nil, "stack underflow: negate needs 1 numbers, the stack holds 0"},
+		{nil, rpn.Dup, nil, "stack underflow"},
+		{[]float64{1}, rpn.Swap, nil, "stack underflow"},
+		{[]float64{1, 0}, rpn.Divide, nil, "cannot divide 1 by zero"},
+		{[]float64{-4}, rpn.Sqrt, nil, "cannot take the square root of -4"},
+		{[]float64{-8, 1.0 / 3}, rpn.Power, nil, "power of -8 and 0.3333333333333333 is not a finite number"},
+		{[]float64{1e308, 10}, rpn.Multiply, nil, "multiply of 1e+308 and 10 is not a finite number"},
+		{[]float64{0, -1}, rpn.Power, nil, "is not a finite number"},
+		{[]float64{1, 2}, rpn.Op(42), nil, "unknown operation Op(42)"},
+	}
+	for _, tt := range tests {
+		m := &rpn.Machine{Stack: append([]float64(nil), tt.stack...)}
+		err := m.Apply(tt.op)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%v %v: error %v, want %q", tt.stack, tt.op, err, tt.err)
+			}
+			// A failed operation leaves the stack unchanged.
+			if !reflect.DeepEqual(m.Stack, tt.stack) {
+				t.Errorf("%v %v failed but left the stack %v", tt.stack, tt.op, m.Stack)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v %v failed: %v", tt.stack, tt.op, err)
+			continue
+		}
+		if !reflect.DeepEqual(m.Stack, tt.want) {
+			t.Errorf("%v %v = %v, want %v", tt.stack, tt.op, m.Stack, tt.want)
+		}
+	}
+}
+
+func TestProgram(t *testing.T) {
+	// (3 + 4) * 2, stored in x, then sqrt(x + 2) + x.
+	var m rpn.Machine
+	steps := []func() error{
+		func() error { return m.Push(3) },
+		func() error { return m.Push(4) },
+		func() error { return m.Apply(rpn.Add) },
+		func() error { return m.Push(2) },
+		func() error { return m.Apply(rpn.Multiply) },
+		func() error { return m.Store("x") },
+		func() error { return m.Push(2) },
+		func() error { return m.Apply(rpn.Add) },
+		func() error { return m.Apply(rpn.Sqrt) },
+		func() error { return m.Recall("x") },
+		func() error { return m.Apply(rpn.Add) },
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d failed: %v", i+1, err)
+		}
+	}
+	if want := []float64{18}; !reflect.DeepEqual(m.Stack, want) {
+		t.Errorf("the stack is %v, want %v", m.Stack, want)
+	}
+	if want := map[string]float64{"x": 14}; !reflect.DeepEqual(m.Registers, want) {
+		t.Errorf("the registers are %v, want %v", m.Registers, want)
+	}
+	if err := m.Pop(); err != nil || len(m.Stack) != 0 {
+		t.Errorf("Pop = %v, leaving %v", err, m.Stack)
+	}
+	if err := m.Pop(); !errors.Is(err, rpn.ErrUnderflow) {
+		t.Errorf("Pop of an empty stack = %v, want %v", err, rpn.ErrUnderflow)
+	}
+}
+
+func TestPush(t *testing.T) {
+	var m rpn.Machine
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if err := m.Push(v); err == nil || len(m.Stack) != 0 {
+			t.Errorf("Push(%v) = %v, leaving %v", v, err, m.Stack)
+		}
+	}
+	for i := 0; i < rpn.MaxDepth; i++ {
+		if err := m.Push(float64(i)); err != nil {
+			t.Fatalf("push %d failed: %v", i+1, err)
+		}
+	}
+	if err := m.Push(1); !errors.Is(err, rpn.ErrOverflow) {
+		t.Errorf("Push onto a full stack = %v, want %v", err, rpn.ErrOverflow)
+	}
+	if err := m.Apply(rpn.Dup); !errors.Is(err, rpn.ErrOverflow) || len(m.Stack) != rpn.MaxDepth {
+		t.Errorf("Dup of a full stack = %v, leaving %d numbers, want %v", err, len(m.Stack), rpn.ErrOverflow)
+	}
+}
+
+func TestRegisters(t *testing.T) {
+	var m rpn.Machine
+	if err := m.Store("x"); !errors.Is(err, rpn.ErrUnderflow) {
+		t.Errorf("Store from an empty stack = %v, want %v", err, rpn.ErrUnderflow)
+	}
+	if err := m.Recall("x"); err == nil || !strings.Contains(err.Error(), `register "x" is empty`) {
+		t.Errorf("Recall of an empty register = %v", err)
+	}
+	m.Push(1)
+	for _, name := range []string{"", "1x", "a-b", "é", strings.Repeat("a", 33)} {
+		if err := m.Store(name); err == nil || !strings.Contains(err.Error(), "invalid register name") {
+			t.Errorf("Store(%q) = %v, want an invalid name error", name, err)
+		}
+	}
+	for _, name := range []string{"_", "x1", "Total_2", strings.Repeat("a", 32)} {
+		if err := m.Store(name); err != nil {
+			t.Errorf("Store(%q) failed: %v", name, err)
+		}
+	}
+
+	m.Registers = nil
+	for i := 0; i < rpn.MaxRegisters; i++ {
+		if err := m.Store(fmt.Sprintf("r%d", i)); err != nil {
+			t.Fatalf("store %d failed: %v", i+1, err)
+		}
+	}
+	if err := m.Store("one_more"); err == nil || len(m.Registers) != rpn.MaxRegisters {
+		t.Errorf("Store beyond %d registers = %v, leaving %d", rpn.MaxRegisters, err, len(m.Registers))
+	}
+	// Overwriting a register needs no room.
+	m.Push(2)
+	if err := m.Store("r0"); err != nil || m.Registers["r0"] != 2 {
+		t.Errorf("overwriting a register = %v, r0 is %v", err, m.Registers["r0"])
+	}
+}
@@ -0,0 +1,213 @@
+// Package stats computes summary statistics over a stream of numbers in
+// constant memory: Welford's algorithm for the mean and variance, and the P²
+// algorithm of Jain and Chlamtac for approximate percentiles.
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Summary accumulates statistics over the numbers added to it. The zero
+// value has no percentiles; use New to track some.
+type Summary struct {
+	count    int64
+	mean     float64
+	m2       float64
+	min, max float64
+
+	quantiles []*p2
+}
+
+// New returns a Summary estimating the given percentiles, each between 0 and
+// 100.
+func New(percentiles ...float64) *Summary {
+	s := &Summary{}
+	for _, p := range percentiles {
+		s.quantiles = append(s.quantiles, newP2(p/100))
+	}
+	return s
+}
+
+// Add adds x to the summary.
+func (s *Summary) Add(x float64) {
+	s.count++
+	if s.count == 1 {
+		s.min, s.max = x, x
+	} else {
+		s.min = math.Min(s.min, x)
+		s.max = math.Max(s.max, x)
+	}
+	delta := x - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (x - s.mean)
+
+	for _, q := range s.quantiles {
+		q.add(x)
+	}
+}
+
+// Count returns how many numbers were added.
+func (s *Summary) Count() int64 { return s.count }
+
+// Mean returns the arithmetic mean, or NaN if nothing was added.
+func (s *Summary) Mean() float64 {
+	if s.count == 0 {
+		return math.NaN()
+	}
+	return s.mean
+}
+
+// Variance returns the sample variance, 0 for a single number, or NaN if
+// nothing was added.
+func (s *Summary) Variance() float64 {
+	switch s.count {
+	case 0:
+		return math.NaN()
+	case 1:
+		return 0
+	}
+	return s.m2 / float64(s.count-1)
+}
+
+// StdDev returns the sample standard deviation.
+func (s *Summary) StdDev() float64 { return math.Sqrt(s.Variance()) }
+
+// Min returns the smallest number added, or NaN if nothing was added.
+func (s *Summary) Min() float64 {
+	if s.count == 0 {
+		return math.NaN()
+	}
+	return s.min
+}
+
+// Max returns the largest number added, or NaN if nothing was added.
+func (s *Summary) Max() float64 {
+	if s.count == 0 {
+		return math.NaN()
+	}
+	return s.max
+}
+
+// Percentile is the estimated value of a percentile.
+type Percentile struct {
+	Percentile float64
+	Value      float64
+}
+
+// Percentiles returns the estimates of the percentiles passed to New, in the
+// same order. Estimates are exact for up to five numbers.
+func (s *Summary) Percentiles() []Percentile {
+	ps := make([]Percentile, len(s.quantiles))
+	for i, q := range s.quantiles {
+		v := q.value()
+		switch {
+		case s.count == 0:
+			v = math.NaN()
+		case q.p <= 0:
+			v = s.min
+		case q.p >= 1:
+			v = s.max
+		}
+		ps[i] = Percentile{Percentile: q.p * 100, Value: v}
+	}
+	return ps
+}
+
+// p2 estimates the p-quantile of a stream with five markers, as described in
+// "The P² algorithm for dynamic calculation of quantiles and histograms
+// without storing observations" (Jain and Chlamtac, 1985).
+type p2 struct {
+	p     float64
+	count int
+	q     [5]float64 // marker heights
+	n     [5]float64 // marker positions
+	ns    [5]float64 // desired marker positions
+	dn    [5]float64 // increments of the desired positions
+}
+
+func newP2(p float64) *p2 {
+	return &p2{
+		p:  p,
+		n:  [5]float64{1, 2, 3, 4, 5},
+		ns: [5]float64{1, 1 + 2*p, 1 + 4*p, 3 + 2*p, 5},
+		dn: [5]float64{0, p / 2, p, (1 + p) / 2, 1},
+	}
+}
+
+func (e *p2) add(x float64) {
+	if e.count < 5 {
+		e.q[e.count] = x
+		e.count++
+		if e.count == 5 {
+			sort.Float64s(e.q[:])
+		}
+		return
+	}
+	e.count++
+
+	var k int
+	switch {
+	case x < e.q[0]:
+		e.q[0] = x
+		k = 0
+	case x >= e.q[4]:
+		e.q[4] = x
+		k = 3
+	default:
+		for k = 0; k < 3 && x >= e.q[k+1]; k++ {
+		}
+	}
+	for i := k + 1; i < 5; i++ {
+		e.n[i]++
+	}
+	for i := range e.ns {
+		e.ns[i] += e.dn[i]
+	}
+
+	for i := 1; i <= 3; i++ {
+		d := e.ns[i] - e.n[i]
+		if (d >= 1 && e.n[i+1]-e.n[i] > 1) || (d <= -1 && e.n[i-1]-e.n[i] < -1) {
+			sign := math.Copysign(1, d)
+			q := e.parabolic(i, sign)
+			if e.q[i-1] < q && q < e.q[i+1] {
+				e.q[i] = q
+			} else {
+				e.q[i] = e.linear(i, sign)
+			}
+			e.n[i] += sign
+		}
+	}
+}
+
+func (e *p2) parabolic(i int, d float64) float64 {
+	return e.q[i] + d/(e.n[i+1]-e.n[i-1])*
+		((e.n[i]-e.n[i-1]+d)*(e.q[i+1]-e.q[i])/(e.n[i+1]-e.n[i])+
+			(e.n[i+1]-e.n[i]-d)*(e.q[i]-e.q[i-1])/(e.n[i]-e.n[i-1]))
+}
+
+func (e *p2) linear(i int, d float64) float64 {
+	j := i + int(d)
+	return e.q[i] + d*(e.q[j]-e.q[i])/(e.n[j]-e.n[i])
+}
+
+// value returns the estimate, interpolating between the observations while
+// there are at most five of them: the middle marker only starts at the
+// p-quantile when p is 0.5.
+func (e *p2) value() float64 {
+	if e.count > 5 {
+		return e.q[2]
+	}
+	if e.count == 0 {
+		return math.NaN()
+	}
+	obs := make([]float64, e.count)
+	copy(obs, e.q[:e.count])
+	sort.Float64s(obs)
+	pos := e.p * float64(e.count-1)
+	lo := int(math.Floor(pos))
+	if lo >= e.count-1 {
+		return obs[e.count-1]
+	}
+	return obs[lo] + (pos-float64(lo))*(obs[lo+1]-obs[lo])
+}
@@ -0,0 +1,133 @@
+package stats_test
+
+import (
+	"grpc-go-course/calculator/stats"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestMeanVariance(t *testing.T) {
+	tests := []struct {
+		xs             []float64
+		mean, variance float64
+		min, max       float64
+		tolerance      float64
+	}{
+		{[]float64{7}, 7, 0, 7, 7, 0},
+		{[]float64{1, 2}, 1.5, 0.5, 1, 2, 0},
+		{[]float64{2, 4, 4, 4, 5, 5, 7, 9}, 5, 32.0 / 7, 2, 9, 1e-15},
+		{[]float64{-1, 1, -1, 1}, 0, 4.0 / 3, -1, 1, 1e-15},
+		// A large offset, which cancels out catastrophically in the naive
+		// sum of squares formula.
+		{[]float64{1e9 + 2, 1e9 + 4, 1e9 + 4, 1e9 + 4, 1e9 + 5, 1e9 + 5, 1e9 + 7, 1e9 + 9}, 1e9 + 5, 32.0 / 7, 1e9 + 2, 1e9 + 9, 1e-9},
+	}
+	for _, tt := range tests {
+		s := stats.New()
+		for _, x := range tt.xs {
+			s.Add(x)
+		}
+		if s.Count() != int64(len(tt.xs)) {
+			t.Errorf("%v: Count() = %d, want %d", tt.xs, s.Count(), len(tt.xs))
+		}
+		if got := s.Mean(); math.Abs(got-tt.mean) > tt.tolerance*math.Abs(tt.mean) {
+			t.Errorf("%v: Mean() = %v, want %v", tt.xs, got, tt.mean)
+		}
+		if got := s.Variance(); math.Abs(got-tt.variance) > tt.tolerance*math.Max(1, math.Abs(tt.mean)) {
+			t.Errorf("%v: Variance() = %v, want %v", tt.xs, got, tt.variance)
+		}
+		if got, want := s.StdDev(), math.Sqrt(s.Variance()); got != want {
+			t.Errorf("%v: StdDev() = %v, want %v", tt.xs, got, want)
+		}
+		if s.Min() != tt.min || s.Max() != tt.max {
+			t.Errorf("%v: Min(), Max() = %v, %v, want %v, %v", tt.xs, s.Min(), s.Max(), tt.min, tt.max)
+		}
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	s := stats.New(50)
+	for name, v := range map[string]float64{
+		"Mean":       s.Mean(),
+		"Variance":   s.Variance(),
+		"StdDev":     s.StdDev(),
+		"Min":        s.Min(),
+		"Max":        s.Max(),
+		"Percentile": s.Percentiles()[0].Value,
+	} {
+		if !math.IsNaN(v) {
+			t.Errorf("%s() of nothing = %v, want NaN", name, v)
+		}
+	}
+	var zero stats.Summary
+	zero.Add(1)
+	if ps := zero.Percentiles(); len(ps) != 0 {
+		t.Errorf("the zero Summary has percentiles %v", ps)
+	}
+}
+
+// TestFewPercentiles checks that percentiles of at most five numbers are
+// interpolated exactly between them, whatever order they come in.
+func TestFewPercentiles(t *testing.T) {
+	percentiles := []float64{0, 10, 25, 50, 75, 90, 100}
+	tests := []struct {
+		xs   []float64
+		want []float64
+	}{
+		{[]float64{4}, []float64{4, 4, 4, 4, 4, 4, 4}},
+		{[]float64{3, 1}, []float64{1, 1.2, 1.5, 2, 2.5, 2.8, 3}},
+		{[]float64{30, 10, 20}, []float64{10, 12, 15, 20, 25, 28, 30}},
+		{[]float64{4, 1, 3, 2}, []float64{1, 1.3, 1.75, 2.5, 3.25, 3.7, 4}},
+		{[]float64{5, 1, 4, 2, 3}, []float64{1, 1.4, 2, 3, 4, 4.6, 5}},
+	}
+	for _, tt := range tests {
+		s := stats.New(percentiles...)
+		for _, x := range tt.xs {
+			s.Add(x)
+		}
+		for i, p := range s.Percentiles() {
+			if p.Percentile != percentiles[i] {
+				t.Errorf("%v: percentile %d is p%v, want p%v", tt.xs, i, p.Percentile, percentiles[i])
+			}
+			if math.Abs(p.Value-tt.want[i]) > 1e-12 {
+				t.Errorf("%v: p%v = %v, want %v", tt.xs, p.Percentile, p.Value, tt.want[i])
+			}
+		}
+	}
+}
+
+// TestP2 checks the P² estimates against the exact percentiles of long
+// streams, in terms of rank: the fraction of the numbers below an estimate
+// must be within 1% of its percentile.
+func TestP2(t *testing.T) {
+	percentiles := []float64{1, 10, 25, 50, 75, 90, 99}
+	r := rand.New(rand.NewSource(1))
+	streams := map[string]func(i int) float64{
+		"uniform":     func(int) float64 { return r.Float64() },
+		"normal":      func(int) float64 { return r.NormFloat64()*10 + 100 },
+		"exponential": func(int) float64 { return r.ExpFloat64() },
+		"ascending":   func(i int) float64 { return float64(i) },
+		"descending":  func(i int) float64 { return float64(-i) },
+	}
+	for name, next := range streams {
+		s := stats.New(percentiles...)
+		xs := make([]float64, 100000)
+		for i := range xs {
+			xs[i] = next(i)
+			s.Add(xs[i])
+		}
+		sort.Float64s(xs)
+
+		for _, p := range s.Percentiles() {
+			if p.Value < xs[0] || p.Value > xs[len(xs)-1] {
+				t.Errorf("%s: p%v = %v is outside [%v, %v]", name, p.Percentile, p.Value, xs[0], xs[len(xs)-1])
+				continue
+			}
+			rank := float64(sort.SearchFloat64s(xs, p.Value)) / float64(len(xs)) * 100
+			if math.Abs(rank-p.Percentile) > 1 {
+				t.Errorf("%s: p%v = %v has rank %.2f%%, want %v%% ± 1", name, p.Percentile, p.Value, rank, p.Percentile)
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package units
+
+// builtin is the table of the units every registry starts with, in the format
+// read by Registry.Load. The base unit of each dimension comes first.
+const builtin = `
+# dimension    names                                   factor          offset
+
+length         m,meter,meters,metre,metres             1
+length         km,kilometer,kilometers,kilometre,kilometres 1000
+length         cm,centimeter,centimeters,centimetre,centimetres 1/100
+length         mm,millimeter,millimeters,millimetre,millimetres 1/1000
+length         um,micrometer,micrometers,micron,microns 1/1000000
+length         nm,nanometer,nanometers                 1/1000000000
+length         in,inch,inches                          0.0254
+length         ft,foot,feet                            0.3048
+length         yd,yard,yards                           0.9144
+length         mi,mile,miles                           1609.344
+length         nmi,nautical_mile,nautical_miles        1852
+length         au,astronomical_unit                    149597870700
+length         ly,light_year,light_years               9460730472580800
+
+mass           kg,kilogram,kilograms                   1
+mass           g,gram,grams                            1/1000
+mass           mg,milligram,milligrams                 1/1000000
+mass           ug,microgram,micrograms                 1/1000000000
+mass           t,tonne,tonnes                          1000
+mass           lb,pound,pounds                         0.45359237
+mass           oz,ounce,ounces                         0.028349523125
+mass           st,stone,stones                         6.35029318
+mass           short_ton,short_tons                    907.18474
+mass           long_ton,long_tons                      1016.0469088
+
+time           s,sec,second,seconds                    1
+time           ms,millisecond,milliseconds             1/1000
+time           us,microsecond,microseconds             1/1000000
+time           ns,nanosecond,nanoseconds               1/1000000000
+time           min,minute,minutes                      60
+time           h,hr,hour,hours                         3600
+time           d,day,days                              86400
+time           wk,week,weeks                           604800
+time           yr,year,years                           31557600
+
+temperature    K,kelvin                                1
+temperature    degC,celsius                            1               5463/20
+temperature    degF,fahrenheit                         5/9             45967/180
+temperature    degR,rankine                            5/9
+
+# Data sizes use the decimal prefixes for kB, MB... and the binary ones for
+# KiB, MiB...
+data           B,byte,bytes                            1
+data           bit,bits                                1/8
+data           kB,kilobyte,kilobytes                   1000
+data           MB,megabyte,megabytes                   1000000
+data           GB,gigabyte,gigabytes                   1000000000
+data           TB,terabyte,terabytes                   1000000000000
+data           PB,petabyte,petabytes                   1000000000000000
+data           KiB,kibibyte,kibibytes                  1024
+data           MiB,mebibyte,mebibytes                  1048576
+data           GiB,gibibyte,gibibytes                  1073741824
+data           TiB,tebibyte,tebibytes                  1099511627776
+data           PiB,pebibyte,pebibytes                  1125899906842624
+data           kbit,kilobit,kilobits                   125
+data           Mbit,megabit,megabits                   125000
+data           Gbit,gigabit,gigabits                   125000000
+
+area           m2,square_meter,square_meters           1
+area           km2,square_kilometer,square_kilometers  1000000
+area           cm2,square_centimeter,square_centimeters 1/10000
+area           ha,hectare,hectares                     10000
+area           in2,square_inch,square_inches           0.00064516
+area           ft2,square_foot,square_feet             0.09290304
+area           yd2,square_yard,square_yards            0.83612736
+area           ac,acre,acres                           4046.8564224
+area           mi2,square_mile,square_miles            2589988.110336
+
+volume         m3,cubic_meter,cubic_meters             1
+volume         L,l,liter,liters,litre,litres           1/1000
+volume         mL,ml,milliliter,milliliters,millilitre,millilitres 1/1000000
+volume         cm3,cubic_centimeter,cubic_centimeters  1/1000000
+volume         in3,cubic_inch,cubic_inches             0.000016387064
+volume         ft3,cubic_foot,cubic_feet               0.028316846592
+volume         gal,gallon,gallons                      0.003785411784
+volume         qt,quart,quarts                         0.000946352946
+volume         pt,pint,pints                           0.000473176473
+volume         fl_oz,fluid_ounce,fluid_ounces          0.0000295735295625
+volume         imp_gal,imperial_gallon,imperial_gallons 0.00454609
+
+speed          m/s,meter_per_second,meters_per_second  1
+speed          km/h,kph,kilometer_per_hour,kilometers_per_hour 5/18
+speed          mph,mile_per_hour,miles_per_hour        0.44704
+speed          ft/s,foot_per_second,feet_per_second    0.3048
+speed          kn,knot,knots                           463/900
+
+energy         J,joule,joules                          1
+energy         kJ,kilojoule,kilojoules                 1000
+energy         cal,calorie,calories                    4.184
+energy         kcal,kilocalorie,kilocalories           4184
+energy         Wh,watt_hour,watt_hours                 3600
+energy         kWh,kilowatt_hour,kilowatt_hours        3600000
+energy         eV,electronvolt,electronvolts           1.602176634e-19
+energy         BTU,btu                                 1055.05585262
+
+power          W,watt,watts                            1
+power          kW,kilowatt,kilowatts                   1000
+power          MW,megawatt,megawatts                   1000000
+power          hp,horsepower                           745.69987158227022
+
+pressure       Pa,pascal,pascals                       1
+pressure       kPa,kilopascal,kilopascals              1000
+pressure       bar,bars                                100000
+pressure       atm,atmosphere,atmospheres              101325
+pressure       psi                                     6894.757293168361
+pressure       mmHg                                    133.322387415
+
+angle          rad,radian,radians                      1
+angle          deg,degree,degrees                      0.017453292519943295769236907684886
+angle          grad,gradian,gradians                   0.015707963267948966192313216916398
+angle          turn,turns,revolution,revolutions       6.283185307179586476925286766559
+
+frequency      Hz,hertz                                1
+frequency      kHz,kilohertz                           1000
+frequency      MHz,megahertz                           1000000
+frequency      GHz,gigahertz                           1000000000
+frequency      rpm                                     1/60
+`
@@ -0,0 +1,177 @@
+// Package units converts quantities between units of the same dimension,
+// such as length or temperature, using a registry of units read from tables.
+package units
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+)
+
+var (
+	// ErrUnknownUnit is returned for units missing from the registry.
+	ErrUnknownUnit = errors.New("unknown unit")
+	// ErrIncompatible is returned when converting between units of different
+	// dimensions.
+	ErrIncompatible = errors.New("incompatible units")
+)
+
+// Unit is a unit of measurement. A value v in the unit is v*Factor + Offset in
+// the base unit of its dimension, the one with a factor of 1 and no offset.
+// Factor and Offset are exact, so that conversions only round once.
+type Unit struct {
+	// Name is the canonical name of the unit, its first name in the table.
+	Name      string
+	Dimension string
+	Factor    *big.Rat
+	Offset    *big.Rat
+}
+
+// Registry maps the names of units to units. It is safe for concurrent use
+// once loaded.
+type Registry struct {
+	units map[string]*Unit
+}
+
+// New returns a registry of the builtin units.
+func New() *Registry {
+	r := &Registry{units: make(map[string]*Unit)}
+	if err := r.Load(strings.NewReader(builtin), "builtin"); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// LoadFile adds the units of the table in the named file.
+func (r *Registry) LoadFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return r.Load(f, name)
+}
+
+// Load adds the units of a table read from rd. Each line of the table defines
+// a unit as its dimension, its names separated by commas, its factor and
+// optionally its offset, separated by whitespace:
+//
+//	# dimension  names             factor   offset
+//	length       m,meter,meters    1
+//	length       ft,foot,feet      0.3048
+//	temperature  degF,fahrenheit   5/9      45967/180
+//
+// Factors and offsets are decimals or fractions. Blank lines and lines
+// starting with # are ignored. Names must not already be defined. source
+// names the table in errors.
+func (r *Registry) Load(rd io.Reader, source string) error {
+	scanner := bufio.NewScanner(rd)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if err := r.define(fields); err != nil {
+			return fmt.Errorf("%s:%d: %v", source, line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func (r *Registry) define(fields []string) error {
+	if len(fields) < 3 || len(fields) > 4 {
+		return fmt.Errorf("expected a dimension, names, a factor and an optional offset, got %d fields", len(fields))
+	}
+	u := &Unit{Dimension: fields[0], Offset: new(big.Rat)}
+	var err error
+	if u.Factor, err = parseNumber(fields[2]); err != nil {
+		return err
+	}
+	if u.Factor.Sign() <= 0 {
+		return fmt.Errorf("factor must be positive, got %s", fields[2])
+	}
+	if len(fields) == 4 {
+		if u.Offset, err = parseNumber(fields[3]); err != nil {
+			return err
+		}
+	}
+
+	names := strings.Split(fields[1], ",")
+	for _, name := range names {
+		if name == "" {
+			return fmt.Errorf("empty unit name in %q", fields[1])
+		}
+		if prev, ok := r.units[name]; ok {
+			return fmt.Errorf("unit %q is already defined as a unit of %s", name, prev.Dimension)
+		}
+	}
+	u.Name = names[0]
+	for _, name := range names {
+		r.units[name] = u
+	}
+	return nil
+}
+
+// maxNumberLength bounds the numbers of a table, since big.Rat would accept
+// exponents such as 1e1000000000.
+const maxNumberLength = 100
+
+// parseNumber parses a decimal such as "0.3048" or a fraction such as "5/9".
+func parseNumber(s string) (*big.Rat, error) {
+	if len(s) > maxNumberLength || strings.ContainsAny(s, "eE") && strings.Contains(s, "/") {
+		return nil, fmt.Errorf("invalid number %q", s)
+	}
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		var exp int
+		if _, err := fmt.Sscan(s[i+1:], &exp); err != nil || exp > 300 || exp < -300 {
+			return nil, fmt.Errorf("invalid number %q", s)
+		}
+	}
+	q, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid number %q", s)
+	}
+	return q, nil
+}
+
+// Lookup returns the unit with the given name.
+func (r *Registry) Lookup(name string) (*Unit, error) {
+	u, ok := r.units[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownUnit, name)
+	}
+	return u, nil
+}
+
+// Convert converts value from one unit to another of the same dimension.
+func (r *Registry) Convert(value float64, from, to string) (float64, error) {
+	f, err := r.Lookup(from)
+	if err != nil {
+		return 0, err
+	}
+	t, err := r.Lookup(to)
+	if err != nil {
+		return 0, err
+	}
+	if f.Dimension != t.Dimension {
+		return 0, fmt.Errorf("%w: %s is a unit of %s, %s of %s", ErrIncompatible, f.Name, f.Dimension, t.Name, t.Dimension)
+	}
+	if f == t {
+		return value, nil
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("value is not finite: %v", value)
+	}
+	q := new(big.Rat).SetFloat64(value)
+	q.Mul(q, f.Factor)
+	q.Add(q, f.Offset)
+	q.Sub(q, t.Offset)
+	q.Quo(q, t.Factor)
+	res, _ := q.Float64()
+	return res, nil
+}
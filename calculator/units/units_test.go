@@ -0,0 +1,160 @@
+package units_test
+
+import (
+	"errors"
+	"grpc-go-course/calculator/units"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		value    float64
+		from, to string
+		want     float64
+	}{
+		// Affine temperature scales.
+		{0, "degC", "degF", 32},
+		{100, "degC", "degF", 212},
+		{-40, "degC", "degF", -40},
+		{-40, "degF", "celsius", -40},
+		{32, "fahrenheit", "degC", 0},
+		{98.6, "degF", "degC", 37},
+		{0, "K", "degC", -273.15},
+		{0, "degC", "K", 273.15},
+		{0, "degF", "K", 255.37222222222223},
+		{0, "degF", "degR", 459.67},
+		{491.67, "degR", "degC", 0},
+		{1, "degC", "degC", 1},
+		// Linear units, and their aliases.
+		{1, "mi", "m", 1609.344},
+		{1, "ft", "in", 12},
+		{3, "feet", "yard", 1},
+		{1, "KiB", "kB", 1.024},
+		{8, "bits", "byte", 1},
+		{1.5, "h", "min", 90},
+		{-2, "km", "m", -2000},
+		{0, "lb", "kg", 0},
+		{1e300, "ly", "m", math.Inf(1)},
+	}
+	r := units.New()
+	for _, tt := range tests {
+		got, err := r.Convert(tt.value, tt.from, tt.to)
+		if err != nil {
+			t.Errorf("Convert(%v, %s, %s) failed: %v", tt.value, tt.from, tt.to, err)
+			continue
+		}
+		if got != tt.want && math.Abs(got-tt.want) > 1e-12*math.Max(1, math.Abs(tt.want)) {
+			t.Errorf("Convert(%v, %s, %s) = %v, want %v", tt.value, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	tests := []struct {
+		value    float64
+		from, to string
+		want     error
+		msg      string
+	}{
+		{1, "degC", "m", units.ErrIncompatible, "degC is a unit of temperature, m of length"},
+		{1, "kg", "s", units.ErrIncompatible, "kg is a unit of mass, s of time"},
+		{1, "furlong", "m", units.ErrUnknownUnit, `"furlong"`},
+		{1, "m", "furlong", units.ErrUnknownUnit, `"furlong"`},
+		// Names are case sensitive.
+		{1, "degc", "degF", units.ErrUnknownUnit, `"degc"`},
+		{math.NaN(), "m", "ft", nil, "not finite"},
+		{math.Inf(-1), "degC", "degF", nil, "not finite"},
+	}
+	r := units.New()
+	for _, tt := range tests {
+		_, err := r.Convert(tt.value, tt.from, tt.to)
+		if err == nil || tt.want != nil && !errors.Is(err, tt.want) || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("Convert(%v, %s, %s) = %v, want %v with %q", tt.value, tt.from, tt.to, err, tt.want, tt.msg)
+		}
+	}
+}
+
+// writeTable writes a table of units to a temporary file and returns its
+// name.
+func writeTable(t *testing.T, table string) string {
+	name := filepath.Join(t.TempDir(), "units.txt")
+	if err := ioutil.WriteFile(name, []byte(table), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestLoadFile(t *testing.T) {
+	name := writeTable(t, `
+# Old units.
+length     furlong,furlongs   201.168
+  # Indented comments and blank lines are ignored.
+
+time       fortnight          1209600
+temperature degRe,reaumur     5/4         5463/20
+`)
+	r := units.New()
+	if err := r.LoadFile(name); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		value    float64
+		from, to string
+		want     float64
+	}{
+		{1, "furlongs", "m", 201.168},
+		{1, "mi", "furlong", 8},
+		{1, "fortnight", "d", 14},
+		{0, "degRe", "degC", 0},
+		{80, "reaumur", "degF", 212},
+	}
+	for _, tt := range tests {
+		got, err := r.Convert(tt.value, tt.from, tt.to)
+		if err != nil || math.Abs(got-tt.want) > 1e-12*math.Max(1, math.Abs(tt.want)) {
+			t.Errorf("Convert(%v, %s, %s) = %v, %v, want %v", tt.value, tt.from, tt.to, got, err, tt.want)
+		}
+	}
+	u, err := r.Lookup("furlongs")
+	if err != nil || u.Name != "furlong" || u.Dimension != "length" {
+		t.Errorf("Lookup(furlongs) = %+v, %v, want the unit furlong of length", u, err)
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	tests := []struct {
+		table string
+		// want is the start of the error, after the file name.
+		want string
+	}{
+		{"length furlong", ":1: expected a dimension, names, a factor and an optional offset, got 2 fields"},
+		{"\n\nlength furlong 201.168 0 0", ":3: expected a dimension, names, a factor and an optional offset, got 5 fields"},
+		{"# comment\nlength furlong twenty", `:2: invalid number "twenty"`},
+		{"length furlong 201.168 x", `:1: invalid number "x"`},
+		{"length furlong 1/0", `:1: invalid number "1/0"`},
+		{"length furlong 1e1000", `:1: invalid number "1e1000"`},
+		{"length furlong 1e3/2", `:1: invalid number "1e3/2"`},
+		{"length furlong " + strings.Repeat("1", 101), ":1: invalid number"},
+		{"length furlong 0", ":1: factor must be positive, got 0"},
+		{"length furlong -1", ":1: factor must be positive, got -1"},
+		{"length furlong,,fur 201.168", `:1: empty unit name in "furlong,,fur"`},
+		{"length foot 0.3048", `:1: unit "foot" is already defined as a unit of length`},
+		{"length fur 1\nlength fur 2", `:2: unit "fur" is already defined as a unit of length`},
+	}
+	for _, tt := range tests {
+		name := writeTable(t, tt.table)
+		err := units.New().LoadFile(name)
+		if err == nil || !strings.HasPrefix(err.Error(), name+tt.want) {
+			t.Errorf("LoadFile(%q) = %v, want %s%s", tt.table, err, name, tt.want)
+		}
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing.txt")
+	if err := units.New().LoadFile(missing); !os.IsNotExist(err) {
+		t.Errorf("LoadFile of a missing file = %v, want a not exist error", err)
+	}
+}
@@ -0,0 +1,70 @@
+// Package certs builds TLS configurations from certificate files, and
+// identifies the clients that authenticate with a certificate.
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// ServerConfig returns the TLS configuration of a server presenting the
+// certificate in certFile and keyFile. If clientCAFile is set, clients may
+// present a certificate signed by one of its CAs, which is verified and
+// identifies them; clients without one are still accepted, unidentified.
+func ServerConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if clientCAFile != "" {
+		if cfg.ClientCAs, err = loadPool(clientCAFile); err != nil {
+			return nil, err
+		}
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return cfg, nil
+}
+
+// ClientConfig returns the TLS configuration of a client verifying the server
+// against the CAs in caFile, and presenting the certificate in certFile and
+// keyFile if they are set. serverName overrides the name the server
+// certificate is verified against.
+func ClientConfig(caFile, serverName, certFile, keyFile string) (*tls.Config, error) {
+	roots, err := loadPool(caFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{RootCAs: roots, ServerName: serverName}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// CommonName returns the common name of the verified certificate the peer of
+// state authenticated with, or false if it did not present one.
+func CommonName(state tls.ConnectionState) (string, bool) {
+	if chains := state.VerifiedChains; len(chains) > 0 && len(chains[0]) > 0 {
+		return chains[0][0].Subject.CommonName, true
+	}
+	return "", false
+}
+
+func loadPool(file string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in %s", file)
+	}
+	return pool, nil
+}
@@ -0,0 +1,20 @@
+#!/bin/bash
+# Fails when the committed generated code or OpenAPI documents don't match the
+# protos, e.g. after editing a .proto without running generate.sh. Run it from
+# the repository root in CI.
+
+set -e
+
+./generate.sh
+
+if ! git diff --exit-code --stat -- '*.pb.go' '*.pb.gw.go' '*.swagger.json'; then
+  echo "Generated files are out of date with the protos, run ./generate.sh and commit the result." >&2
+  exit 1
+fi
+
+untracked=$(git ls-files --others --exclude-standard -- '*.pb.go' '*.pb.gw.go' '*.swagger.json')
+if [ -n "$untracked" ]; then
+  echo "Generated files are not committed:" >&2
+  echo "$untracked" >&2
+  exit 1
+fi
@@ -0,0 +1,165 @@
+// Package dial holds the connection flags shared by the command line clients
+// and turns them into a gRPC client connection.
+package dial
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	_ "google.golang.org/grpc/health" // client side health checking
+	"google.golang.org/grpc/resolver"
+	"grpc-go-course/certs"
+	"time"
+)
+
+// Flags are the connection settings of a command line client.
+type Flags struct {
+	// Addr is a host:port address, or a comma separated list of them to
+	// balance calls across several replicas.
+	Addr       string
+	CAFile     string
+	ServerName string
+	// CertFile and KeyFile are the client certificate presented to the
+	// server, if any, to authenticate with.
+	CertFile  string
+	KeyFile   string
+	Plaintext bool
+	Timeout   time.Duration
+	// ServiceConfig is a service config JSON file replacing
+	// DefaultServiceConfig.
+	ServiceConfig string
+	// ResolverFile lists the server addresses, one per line, instead of
+	// Addr. It is watched for changes.
+	ResolverFile string
+	// LBPolicy is the load balancing policy: pick_first, round_robin or
+	// least_request. If empty, the service config's policy applies.
+	LBPolicy string
+	// HealthCheck makes the balancer skip backends whose health service
+	// doesn't report SERVING.
+	HealthCheck bool
+}
+
+// Defaults are the flag defaults of a particular client.
+type Defaults struct {
+	Addr      string
+	CAFile    string
+	Plaintext bool
+	Timeout   time.Duration
+}
+
+// Register defines the connection flags on fs.
+func (f *Flags) Register(fs *flag.FlagSet, d Defaults) {
+	fs.StringVar(&f.Addr, "addr", d.Addr, "address of the server, or a comma separated list of replicas")
+	fs.StringVar(&f.CAFile, "tls-ca", d.CAFile, "CA certificate used to verify the server")
+	fs.StringVar(&f.ServerName, "tls-server-name", "", "override the server name used to verify its certificate")
+	fs.StringVar(&f.CertFile, "tls-cert", "", "client certificate presented to the server, empty for none")
+	fs.StringVar(&f.KeyFile, "tls-key", "", "private key of -tls-cert")
+	fs.BoolVar(&f.Plaintext, "plaintext", d.Plaintext, "connect without TLS")
+	fs.DurationVar(&f.Timeout, "timeout", d.Timeout, "deadline of each call, 0 for none")
+	fs.StringVar(&f.ServiceConfig, "service-config", "", "service config JSON file overriding the default retry and timeout policies")
+	fs.StringVar(&f.ResolverFile, "resolver-file", "", "file listing the server replicas, one address per line (overrides -addr)")
+	fs.StringVar(&f.LBPolicy, "lb", "", "load balancing policy: pick_first, round_robin or "+LeastRequest+" (default: the service config's, round_robin in the default one)")
+	fs.BoolVar(&f.HealthCheck, "health-check", true, "skip replicas whose health service doesn't report SERVING (not with pick_first)")
+}
+
+// Target returns the dial target: Addr itself for a single address, or a
+// target handled by the resolver from ChannelOptions otherwise. The target
+// names the first replica's host, which is then the name its certificate is
+// verified against.
+func (f *Flags) Target() (string, error) {
+	addrs, err := f.addrs()
+	if err != nil {
+		return "", err
+	}
+	if f.ResolverFile == "" && len(addrs) == 1 {
+		return addrs[0], nil
+	}
+	return f.scheme() + ":///" + hostOf(addrs[0]), nil
+}
+
+func (f *Flags) addrs() ([]string, error) {
+	if f.ResolverFile != "" {
+		return readAddressFile(f.ResolverFile)
+	}
+	addrs := splitAddrs(f.Addr)
+	if len(addrs) == 0 {
+		return nil, errors.New("no server address given")
+	}
+	return addrs, nil
+}
+
+func (f *Flags) scheme() string {
+	if f.ResolverFile != "" {
+		return "file"
+	}
+	return "static"
+}
+
+// ChannelOptions returns the dial options selected by the flags, except for
+// transport security: the service config, hedging, name resolution and load
+// balancing.
+func (f *Flags) ChannelOptions() ([]grpc.DialOption, error) {
+	cfg, err := LoadServiceConfig(f.ServiceConfig)
+	if err != nil {
+		return nil, err
+	}
+	if cfg, err = withBalancing(cfg, f.LBPolicy, f.HealthCheck); err != nil {
+		return nil, err
+	}
+	opts, err := ServiceConfigOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var builder resolver.Builder
+	if f.ResolverFile != "" {
+		builder = &fileBuilder{path: f.ResolverFile}
+	} else if addrs := splitAddrs(f.Addr); len(addrs) > 1 {
+		builder = &staticBuilder{addrs: addrs}
+	}
+	if builder != nil {
+		opts = append(opts, grpc.WithResolvers(builder))
+	}
+	return opts, nil
+}
+
+// DialOptions returns all the dial options selected by the flags.
+func (f *Flags) DialOptions() ([]grpc.DialOption, error) {
+	opts, err := f.ChannelOptions()
+	if err != nil {
+		return nil, err
+	}
+	if f.Plaintext {
+		return append(opts, grpc.WithInsecure()), nil
+	}
+	cfg, err := certs.ClientConfig(f.CAFile, f.ServerName, f.CertFile, f.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return append(opts, grpc.WithTransportCredentials(credentials.NewTLS(cfg))), nil
+}
+
+// Dial connects to the server(s) selected by the flags. Extra options are
+// applied after the ones derived from the flags.
+func (f *Flags) Dial(ctx context.Context, extra ...grpc.DialOption) (*grpc.ClientConn, error) {
+	target, err := f.Target()
+	if err != nil {
+		return nil, err
+	}
+	opts, err := f.DialOptions()
+	if err != nil {
+		return nil, err
+	}
+	return grpc.DialContext(ctx, target, append(opts, extra...)...)
+}
+
+// CallContext derives the context of a call from parent, applying the
+// -timeout deadline if one was set.
+func (f *Flags) CallContext(parent context.Context) (context.Context, context.CancelFunc) {
+	if f.Timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, f.Timeout)
+}
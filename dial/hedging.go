@@ -0,0 +1,206 @@
+package dial
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"reflect"
+	"time"
+)
+
+// maxHedgedAttempts caps hedgingPolicy.maxAttempts, as gRPC does for
+// retryPolicy.maxAttempts.
+const maxHedgedAttempts = 5
+
+// hedgingPolicy is the hedgingPolicy of a method config, as defined by the
+// gRPC service config.
+type hedgingPolicy struct {
+	MaxAttempts         int          `json:"maxAttempts"`
+	HedgingDelay        string       `json:"hedgingDelay"`
+	NonFatalStatusCodes []codes.Code `json:"nonFatalStatusCodes"`
+
+	delay time.Duration
+}
+
+func (p *hedgingPolicy) nonFatal(err error) bool {
+	code := status.Code(err)
+	for _, c := range p.NonFatalStatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHedgingPolicies returns the hedging policies in a service config,
+// keyed by "/service/method", or by "/service/" for service wide policies.
+func parseHedgingPolicies(cfg string) (map[string]*hedgingPolicy, error) {
+	var sc struct {
+		MethodConfig []struct {
+			Name []struct {
+				Service string `json:"service"`
+				Method  string `json:"method"`
+			} `json:"name"`
+			HedgingPolicy *hedgingPolicy `json:"hedgingPolicy"`
+		} `json:"methodConfig"`
+	}
+	if err := json.Unmarshal([]byte(cfg), &sc); err != nil {
+		return nil, fmt.Errorf("invalid service config: %v", err)
+	}
+
+	policies := map[string]*hedgingPolicy{}
+	for _, mc := range sc.MethodConfig {
+		p := mc.HedgingPolicy
+		if p == nil {
+			continue
+		}
+		if p.MaxAttempts < 2 {
+			return nil, fmt.Errorf("invalid hedgingPolicy: maxAttempts must be at least 2, got %d", p.MaxAttempts)
+		}
+		if p.MaxAttempts > maxHedgedAttempts {
+			p.MaxAttempts = maxHedgedAttempts
+		}
+		if p.HedgingDelay != "" {
+			d, err := time.ParseDuration(p.HedgingDelay)
+			if err != nil {
+				return nil, fmt.Errorf("invalid hedgingPolicy: hedgingDelay: %v", err)
+			}
+			p.delay = d
+		}
+		for _, name := range mc.Name {
+			policies["/"+name.Service+"/"+name.Method] = p
+		}
+	}
+	return policies, nil
+}
+
+// hedgingInterceptor sends up to MaxAttempts copies of a unary call,
+// starting a new one every HedgingDelay, or right away when an attempt fails
+// with a non fatal status code. The first successful attempt, or the first
+// fatal error, wins and the other attempts are cancelled. Only configure it
+// for idempotent methods, since every attempt may run on the server.
+func hedgingInterceptor(policies map[string]*hedgingPolicy) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		p, ok := policies[method]
+		if !ok {
+			p, ok = policies[method[:len(method)-len(methodName(method))]]
+		}
+		if !ok {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		type result struct {
+			reply   interface{}
+			err     error
+			attempt *attempt
+		}
+		results := make(chan result, p.MaxAttempts)
+		start := func() {
+			r := reflect.New(reflect.TypeOf(reply).Elem()).Interface()
+			a := newAttempt(opts)
+			go func() {
+				err := invoker(ctx, method, req, r, cc, a.opts...)
+				results <- result{r, err, a}
+			}()
+		}
+
+		start()
+		started, finished := 1, 0
+		var lastErr error
+		timer := time.NewTimer(p.delay)
+		defer timer.Stop()
+		for finished < started {
+			select {
+			case <-timer.C:
+				if started < p.MaxAttempts {
+					start()
+					started++
+					timer.Reset(p.delay)
+				}
+			case res := <-results:
+				finished++
+				if res.err == nil {
+					proto.Merge(reply.(proto.Message), res.reply.(proto.Message))
+					res.attempt.report()
+					return nil
+				}
+				lastErr = res.err
+				if !p.nonFatal(res.err) {
+					res.attempt.report()
+					return res.err
+				}
+				if started < p.MaxAttempts {
+					start()
+					started++
+				}
+			}
+		}
+		return lastErr
+	}
+}
+
+// attempt holds the call options of a hedged attempt. The header, trailer
+// and peer the caller asked for are received in copies owned by the attempt,
+// since attempts run concurrently, and only the winner's are reported.
+type attempt struct {
+	opts    []grpc.CallOption
+	header  []*grpc.HeaderCallOption
+	trailer []*grpc.TrailerCallOption
+	peer    []*grpc.PeerCallOption
+}
+
+func newAttempt(opts []grpc.CallOption) *attempt {
+	a := &attempt{opts: make([]grpc.CallOption, len(opts))}
+	for i, opt := range opts {
+		switch o := opt.(type) {
+		case grpc.HeaderCallOption:
+			a.header = append(a.header, &o)
+			opt = grpc.Header(new(metadata.MD))
+		case grpc.TrailerCallOption:
+			a.trailer = append(a.trailer, &o)
+			opt = grpc.Trailer(new(metadata.MD))
+		case grpc.PeerCallOption:
+			a.peer = append(a.peer, &o)
+			opt = grpc.Peer(new(peer.Peer))
+		}
+		a.opts[i] = opt
+	}
+	return a
+}
+
+// report copies what the attempt received to the caller's targets.
+func (a *attempt) report() {
+	hi, ti, pi := 0, 0, 0
+	for _, opt := range a.opts {
+		switch o := opt.(type) {
+		case grpc.HeaderCallOption:
+			*a.header[hi].HeaderAddr = *o.HeaderAddr
+			hi++
+		case grpc.TrailerCallOption:
+			*a.trailer[ti].TrailerAddr = *o.TrailerAddr
+			ti++
+		case grpc.PeerCallOption:
+			*a.peer[pi].PeerAddr = *o.PeerAddr
+			pi++
+		}
+	}
+}
+
+// methodName returns the method part of a "/service/method" name.
+func methodName(fullMethod string) string {
+	for i := len(fullMethod) - 1; i >= 0; i-- {
+		if fullMethod[i] == '/' {
+			return fullMethod[i+1:]
+		}
+	}
+	return fullMethod
+}
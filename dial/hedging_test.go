@@ -0,0 +1,150 @@
+package dial_test
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"grpc-go-course/calculator/calculatorpb"
+	"grpc-go-course/dial"
+	"grpc-go-course/faults"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// hedgingConfig hedges Sum: a new attempt starts every 20ms, or as soon as
+// one fails with UNAVAILABLE, up to 3 attempts.
+const hedgingConfig = `{
+  "methodConfig": [
+    {
+      "name": [{"service": "calculator.CalculatorService", "method": "Sum"}],
+      "hedgingPolicy": {
+        "maxAttempts": 3,
+        "hedgingDelay": "0.02s",
+        "nonFatalStatusCodes": ["UNAVAILABLE"]
+      }
+    }
+  ]
+}`
+
+// sumServer tags each Sum response with the attempt that produced it, in its
+// header, trailer and result. With slowFirst, the first attempt of every Sum
+// takes longer than the hedging delay, so that attempts overlap.
+type sumServer struct {
+	calculatorpb.UnimplementedCalculatorServiceServer
+	slowFirst bool
+	calls     int32
+}
+
+func (s *sumServer) Sum(ctx context.Context, req *calculatorpb.SumRequest) (*calculatorpb.SumResponse, error) {
+	n := atomic.AddInt32(&s.calls, 1)
+	attempt := strconv.Itoa(int(n))
+	grpc.SetHeader(ctx, metadata.Pairs("attempt", attempt))
+	grpc.SetTrailer(ctx, metadata.Pairs("attempt", attempt))
+	if s.slowFirst && n%2 == 1 {
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return &calculatorpb.SumResponse{Result: n}, nil
+}
+
+func (*sumServer) Factorial(ctx context.Context, req *calculatorpb.FactorialRequest) (*calculatorpb.BigIntegerResponse, error) {
+	return &calculatorpb.BigIntegerResponse{Result: "6"}, nil
+}
+
+// alternate fails every other RPC, starting with the first, so that each
+// hedged call fails once, which starts the next attempt at once, then
+// succeeds.
+func alternate() func() float64 {
+	var mu sync.Mutex
+	calls := 0
+	return func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls%2 == 1 {
+			return 0
+		}
+		return 0.99
+	}
+}
+
+func dialHedged(t *testing.T, cfg faults.Config, srv calculatorpb.CalculatorServiceServer) calculatorpb.CalculatorServiceClient {
+	listener := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(cfg.ServerOptions()...)
+	calculatorpb.RegisterCalculatorServiceServer(s, srv)
+	go s.Serve(listener)
+	t.Cleanup(s.Stop)
+
+	opts, err := dial.ServiceConfigOptions(hedgingConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts = append(opts,
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+	)
+	cc, err := grpc.Dial("bufnet", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cc.Close() })
+	return calculatorpb.NewCalculatorServiceClient(cc)
+}
+
+// sumWithMetadata calls Sum, checking that the header and trailer it
+// receives come from the attempt whose result it returns.
+func sumWithMetadata(t *testing.T, c calculatorpb.CalculatorServiceClient) {
+	var header, trailer metadata.MD
+	var p peer.Peer
+	res, err := c.Sum(context.Background(), &calculatorpb.SumRequest{}, grpc.Header(&header), grpc.Trailer(&trailer), grpc.Peer(&p))
+	if err != nil {
+		t.Fatalf("Sum: %v", err)
+	}
+	want := strconv.Itoa(int(res.GetResult()))
+	if got := header.Get("attempt"); len(got) != 1 || got[0] != want {
+		t.Errorf("header attempt = %v, want [%s]", got, want)
+	}
+	if got := trailer.Get("attempt"); len(got) != 1 || got[0] != want {
+		t.Errorf("trailer attempt = %v, want [%s]", got, want)
+	}
+	if p.Addr == nil {
+		t.Error("the peer was not reported")
+	}
+}
+
+func TestHedgingOverlappingAttempts(t *testing.T) {
+	c := dialHedged(t, faults.Config{}, &sumServer{slowFirst: true})
+	for i := 0; i < 5; i++ {
+		start := time.Now()
+		sumWithMetadata(t, c)
+		if d := time.Since(start); d > 500*time.Millisecond {
+			t.Errorf("Sum took %v, the hedged attempt should have answered first", d)
+		}
+	}
+}
+
+func TestHedgingThroughFaults(t *testing.T) {
+	c := dialHedged(t, faults.Config{Rate: 0.5, Code: codes.Unavailable, Rand: alternate()}, &sumServer{})
+	for i := 0; i < 5; i++ {
+		sumWithMetadata(t, c)
+	}
+
+	// Factorial is not hedged, so the injected fault reaches the caller.
+	_, err := c.Factorial(context.Background(), &calculatorpb.FactorialRequest{N: 3})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Factorial error = %v, want code %v", err, codes.Unavailable)
+	}
+}
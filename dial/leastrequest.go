@@ -0,0 +1,88 @@
+package dial
+
+import (
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+// LeastRequest is the name of the least request load balancing policy. It
+// picks two ready backends at random and sends the RPC to the one with fewer
+// RPCs in flight ("power of two choices"), which avoids piling work onto a
+// slow replica the way round_robin does.
+const LeastRequest = "least_request"
+
+func init() {
+	balancer.Register(lrBuilder{})
+}
+
+// lrBuilder builds a base balancer with its own lrPickerBuilder for every
+// ClientConn, so that in flight counts are not shared between connections.
+type lrBuilder struct{}
+
+func (lrBuilder) Name() string {
+	return LeastRequest
+}
+
+func (lrBuilder) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	pb := &lrPickerBuilder{inFlight: map[balancer.SubConn]*int64{}}
+	return base.NewBalancerBuilder(LeastRequest, pb, base.Config{HealthCheck: true}).Build(cc, opts)
+}
+
+type lrPickerBuilder struct {
+	// inFlight counts the RPCs in flight per SubConn. It outlives pickers,
+	// which are rebuilt whenever a SubConn changes state.
+	mu       sync.Mutex
+	inFlight map[balancer.SubConn]*int64
+}
+
+func (b *lrPickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p := &lrPicker{}
+	counters := map[balancer.SubConn]*int64{}
+	for sc := range info.ReadySCs {
+		n, ok := b.inFlight[sc]
+		if !ok {
+			n = new(int64)
+		}
+		counters[sc] = n
+		p.subConns = append(p.subConns, sc)
+		p.inFlight = append(p.inFlight, n)
+	}
+	b.inFlight = counters
+	return p
+}
+
+type lrPicker struct {
+	subConns []balancer.SubConn
+	inFlight []*int64
+}
+
+func (p *lrPicker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	i := rand.Intn(len(p.subConns))
+	if len(p.subConns) > 1 {
+		j := rand.Intn(len(p.subConns) - 1)
+		if j >= i {
+			j++
+		}
+		if atomic.LoadInt64(p.inFlight[j]) < atomic.LoadInt64(p.inFlight[i]) {
+			i = j
+		}
+	}
+
+	n := p.inFlight[i]
+	atomic.AddInt64(n, 1)
+	return balancer.PickResult{
+		SubConn: p.subConns[i],
+		Done: func(balancer.DoneInfo) {
+			atomic.AddInt64(n, -1)
+		},
+	}, nil
+}
@@ -0,0 +1,163 @@
+package dial
+
+import (
+	"bufio"
+	"fmt"
+	"google.golang.org/grpc/resolver"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileResolverInterval is how often a resolver file is checked for changes.
+const fileResolverInterval = 5 * time.Second
+
+// staticBuilder resolves to a fixed list of addresses, as given with
+// -addr host1:port,host2:port.
+type staticBuilder struct {
+	addrs []string
+}
+
+func (b *staticBuilder) Scheme() string { return "static" }
+
+func (b *staticBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	cc.UpdateState(resolver.State{Addresses: toAddresses(b.addrs)})
+	return nopResolver{}, nil
+}
+
+type nopResolver struct{}
+
+func (nopResolver) ResolveNow(resolver.ResolveNowOptions) {}
+func (nopResolver) Close()                                {}
+
+// fileBuilder resolves to the addresses listed in a file, one host:port per
+// line, with blank lines and lines starting with # ignored. The file is
+// watched, so replicas can be added and removed without restarting clients.
+type fileBuilder struct {
+	path string
+}
+
+func (b *fileBuilder) Scheme() string { return "file" }
+
+func (b *fileBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	r := &fileResolver{
+		path: b.path,
+		cc:   cc,
+		now:  make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	if err := r.update(); err != nil {
+		return nil, err
+	}
+	go r.watch()
+	return r, nil
+}
+
+type fileResolver struct {
+	path    string
+	cc      resolver.ClientConn
+	modTime time.Time
+	now     chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+// update pushes the file's addresses to the ClientConn if it changed since
+// the last call.
+func (r *fileResolver) update() error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(r.modTime) {
+		return nil
+	}
+	addrs, err := readAddressFile(r.path)
+	if err != nil {
+		return err
+	}
+	r.modTime = info.ModTime()
+	r.cc.UpdateState(resolver.State{Addresses: toAddresses(addrs)})
+	return nil
+}
+
+func (r *fileResolver) watch() {
+	ticker := time.NewTicker(fileResolverInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+		case <-r.now:
+		}
+		if err := r.update(); err != nil {
+			r.cc.ReportError(err)
+		}
+	}
+}
+
+func (r *fileResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.now <- struct{}{}:
+	default:
+	}
+}
+
+func (r *fileResolver) Close() {
+	r.once.Do(func() { close(r.done) })
+}
+
+func readAddressFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var addrs []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		addrs = append(addrs, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses in %s", path)
+	}
+	return addrs, nil
+}
+
+func splitAddrs(list string) []string {
+	var addrs []string
+	for _, a := range strings.Split(list, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			addrs = append(addrs, a)
+		}
+	}
+	return addrs
+}
+
+func toAddresses(addrs []string) []resolver.Address {
+	res := make([]resolver.Address, len(addrs))
+	for i, a := range addrs {
+		res[i] = resolver.Address{Addr: a}
+	}
+	return res
+}
+
+// hostOf returns the host part of a host:port address.
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
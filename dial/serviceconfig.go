@@ -0,0 +1,114 @@
+package dial
+
+import (
+	"encoding/json"
+	"fmt"
+	"google.golang.org/grpc"
+	"io/ioutil"
+)
+
+// DefaultServiceConfig is the service config used unless -service-config
+// points to another one. It retries the idempotent RPCs when a server is
+// briefly unavailable and bounds them with a timeout. Other RPCs are not
+// idempotent or are long lived streams, so they are left alone. Calls are
+// balanced across replicas with round_robin.
+//
+// Besides the standard fields, a method config may hold a hedgingPolicy,
+// which gRPC-Go does not implement itself; see hedgingInterceptor.
+const DefaultServiceConfig = `{
+  "loadBalancingConfig": [{"round_robin": {}}],
+  "methodConfig": [
+    {
+      "name": [
+        {"service": "blog.BlogService", "method": "ReadBlog"},
+        {"service": "calculator.CalculatorService", "method": "Sum"},
+        {"service": "calculator.CalculatorService", "method": "SquareRoot"}
+      ],
+      "timeout": "5s",
+      "retryPolicy": {
+        "maxAttempts": 4,
+        "initialBackoff": "0.1s",
+        "maxBackoff": "1s",
+        "backoffMultiplier": 2,
+        "retryableStatusCodes": ["UNAVAILABLE"]
+      }
+    },
+    {
+      "name": [
+        {"service": "blog.BlogService", "method": "ListBlogs"}
+      ],
+      "timeout": "30s",
+      "retryPolicy": {
+        "maxAttempts": 4,
+        "initialBackoff": "0.1s",
+        "maxBackoff": "1s",
+        "backoffMultiplier": 2,
+        "retryableStatusCodes": ["UNAVAILABLE"]
+      }
+    }
+  ],
+  "retryThrottling": {
+    "maxTokens": 10,
+    "tokenRatio": 0.1
+  }
+}`
+
+// LoadServiceConfig reads the service config in path, or returns
+// DefaultServiceConfig if path is empty.
+func LoadServiceConfig(path string) (string, error) {
+	if path == "" {
+		return DefaultServiceConfig, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if !json.Valid(data) {
+		return "", fmt.Errorf("service config %s is not valid JSON", path)
+	}
+	return string(data), nil
+}
+
+// withBalancing sets the load balancing policy of a service config, keeping
+// the one in cfg if policy is empty, and turns on client side health checking
+// if healthCheck is set.
+func withBalancing(cfg, policy string, healthCheck bool) (string, error) {
+	var sc map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(cfg), &sc); err != nil {
+		return "", fmt.Errorf("invalid service config: %v", err)
+	}
+	if sc == nil {
+		sc = map[string]json.RawMessage{}
+	}
+	if policy != "" {
+		lb, err := json.Marshal([]map[string]struct{}{{policy: {}}})
+		if err != nil {
+			return "", err
+		}
+		sc["loadBalancingConfig"] = lb
+		delete(sc, "loadBalancingPolicy")
+	}
+	if _, ok := sc["healthCheckConfig"]; healthCheck && !ok {
+		// An empty service name checks the health of the server as a whole.
+		sc["healthCheckConfig"] = json.RawMessage(`{"serviceName": ""}`)
+	}
+	data, err := json.Marshal(sc)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ServiceConfigOptions returns the dial options applying cfg, including
+// hedging for the methods that have a hedgingPolicy.
+func ServiceConfigOptions(cfg string) ([]grpc.DialOption, error) {
+	policies, err := parseHedgingPolicies(cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts := []grpc.DialOption{grpc.WithDefaultServiceConfig(cfg)}
+	if len(policies) > 0 {
+		opts = append(opts, grpc.WithChainUnaryInterceptor(hedgingInterceptor(policies)))
+	}
+	return opts, nil
+}
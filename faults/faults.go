@@ -0,0 +1,107 @@
+// Package faults provides server interceptors that fail or slow down a share
+// of the RPCs, to exercise client retry and hedging policies against a real
+// server.
+package faults
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// Config controls which faults are injected. The zero Config injects none.
+type Config struct {
+	// Rate is the share of RPCs, between 0 and 1, failed with Code.
+	Rate float64
+	Code codes.Code
+	// Latency is added to every RPC before it is handled.
+	Latency time.Duration
+	// Rand returns the numbers in [0, 1) deciding which RPCs fail, one per
+	// RPC. It defaults to math/rand's Float64, and must be safe for
+	// concurrent use.
+	Rand func() float64
+}
+
+// RegisterFlags defines the -fault-* flags on fs.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	c.Code = codes.Unavailable
+	fs.Float64Var(&c.Rate, "fault-rate", 0, "share of RPCs to fail on purpose, between 0 and 1")
+	fs.Var((*codeValue)(&c.Code), "fault-code", "status code of the injected failures")
+	fs.DurationVar(&c.Latency, "fault-latency", 0, "latency added to every RPC")
+}
+
+// Enabled reports whether c injects any fault.
+func (c Config) Enabled() bool {
+	return c.Rate > 0 || c.Latency > 0
+}
+
+// ServerOptions returns the interceptors injecting the faults, or nothing if
+// c is disabled.
+func (c Config) ServerOptions() []grpc.ServerOption {
+	if !c.Enabled() {
+		return nil
+	}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(c.unaryInterceptor),
+		grpc.ChainStreamInterceptor(c.streamInterceptor),
+	}
+}
+
+func (c Config) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := c.inject(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (c Config) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := c.inject(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+func (c Config) inject(ctx context.Context, method string) error {
+	if c.Latency > 0 {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-time.After(c.Latency):
+		}
+	}
+	random := c.Rand
+	if random == nil {
+		random = rand.Float64
+	}
+	if c.Rate > 0 && random() < c.Rate {
+		return status.Errorf(c.Code, "injected fault in %s", method)
+	}
+	return nil
+}
+
+// codeValue is a flag.Value for a status code given by name, as in
+// "UNAVAILABLE", or by number.
+type codeValue codes.Code
+
+func (v *codeValue) String() string {
+	return codes.Code(*v).String()
+}
+
+func (v *codeValue) Set(s string) error {
+	if n, err := strconv.ParseUint(s, 10, 32); err == nil {
+		*v = codeValue(n)
+		return nil
+	}
+	var c codes.Code
+	if err := c.UnmarshalJSON([]byte(strconv.Quote(s))); err != nil {
+		return fmt.Errorf("unknown status code %q", s)
+	}
+	*v = codeValue(c)
+	return nil
+}
@@ -0,0 +1,110 @@
+package faults_test
+
+import (
+	"context"
+	"encoding/json"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"grpc-go-course/calculator/calculatorpb"
+	"grpc-go-course/dial"
+	"grpc-go-course/faults"
+	"net"
+	"sync"
+	"testing"
+)
+
+type calculatorServer struct {
+	calculatorpb.UnimplementedCalculatorServiceServer
+}
+
+func (*calculatorServer) Sum(ctx context.Context, req *calculatorpb.SumRequest) (*calculatorpb.SumResponse, error) {
+	return &calculatorpb.SumResponse{Result: req.GetNum_1() + req.GetNum_2()}, nil
+}
+
+func (*calculatorServer) SquareRoot(ctx context.Context, req *calculatorpb.SquareRootRequest) (*calculatorpb.SquareRootResponse, error) {
+	return &calculatorpb.SquareRootResponse{Result: 3}, nil
+}
+
+func (*calculatorServer) Factorial(ctx context.Context, req *calculatorpb.FactorialRequest) (*calculatorpb.BigIntegerResponse, error) {
+	return &calculatorpb.BigIntegerResponse{Result: "6"}, nil
+}
+
+// alternate fails every other RPC, starting with the first, so that each
+// retried RPC fails once then succeeds.
+func alternate() func() float64 {
+	var mu sync.Mutex
+	calls := 0
+	return func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls%2 == 1 {
+			return 0
+		}
+		return 0.99
+	}
+}
+
+// serviceConfig is the default service config without retry throttling,
+// which would stop retrying once half of the attempts have failed.
+func serviceConfig(t *testing.T) string {
+	var sc map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(dial.DefaultServiceConfig), &sc); err != nil {
+		t.Fatal(err)
+	}
+	delete(sc, "retryThrottling")
+	data, err := json.Marshal(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRetriesThroughFaults(t *testing.T) {
+	cfg := faults.Config{Rate: 0.5, Code: codes.Unavailable, Rand: alternate()}
+	listener := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(cfg.ServerOptions()...)
+	calculatorpb.RegisterCalculatorServiceServer(s, &calculatorServer{})
+	go s.Serve(listener)
+	defer s.Stop()
+
+	opts, err := dial.ServiceConfigOptions(serviceConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts = append(opts,
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+	)
+	cc, err := grpc.Dial("bufnet", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	c := calculatorpb.NewCalculatorServiceClient(cc)
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		sum, err := c.Sum(ctx, &calculatorpb.SumRequest{Num_1: 3, Num_2: 4})
+		if err != nil {
+			t.Fatalf("Sum: %v", err)
+		}
+		if sum.GetResult() != 7 {
+			t.Errorf("Sum = %d, want 7", sum.GetResult())
+		}
+		if _, err := c.SquareRoot(ctx, &calculatorpb.SquareRootRequest{Number: 9}); err != nil {
+			t.Fatalf("SquareRoot: %v", err)
+		}
+	}
+
+	// Factorial has no retry policy, so the injected fault reaches the
+	// caller.
+	_, err = c.Factorial(ctx, &calculatorpb.FactorialRequest{N: 3})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Factorial error = %v, want code %v", err, codes.Unavailable)
+	}
+}
@@ -0,0 +1,152 @@
+// Package gateway holds what the REST/JSON gateways in front of the gRPC
+// services have in common: JSON settings, serving the OpenAPI document and
+// running the HTTP server.
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"google.golang.org/grpc/metadata"
+	"grpc-go-course/certs"
+	"grpc-go-course/history"
+	"grpc-go-course/logging"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// SpecPath is where a gateway serves its OpenAPI document.
+const SpecPath = "/openapi.json"
+
+// shutdownTimeout bounds how long in flight HTTP requests may take to finish
+// once a gateway is asked to stop.
+const shutdownTimeout = 10 * time.Second
+
+// Marshaler encodes responses with the JSON names of the proto fields, and
+// includes fields set to their zero value.
+var Marshaler = &runtime.JSONPb{OrigName: false, EmitDefaults: true}
+
+// NewMux returns a gateway mux using Marshaler. It forwards the identity of
+// the HTTP clients that authenticate with a certificate to the gRPC server
+// under history.ForwardedCallerKey, which the clients cannot set themselves.
+func NewMux() *runtime.ServeMux {
+	return runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, Marshaler),
+		runtime.WithIncomingHeaderMatcher(matchHeader),
+		runtime.WithMetadata(forwardCaller),
+	)
+}
+
+// matchHeader is runtime.DefaultHeaderMatcher, except that it drops the
+// history.ForwardedCallerKey a client sends as Grpc-Metadata-*.
+func matchHeader(header string) (string, bool) {
+	key, ok := runtime.DefaultHeaderMatcher(header)
+	if ok && strings.EqualFold(key, history.ForwardedCallerKey) {
+		return "", false
+	}
+	return key, ok
+}
+
+func forwardCaller(ctx context.Context, r *http.Request) metadata.MD {
+	if r.TLS == nil {
+		return nil
+	}
+	if name, ok := certs.CommonName(*r.TLS); ok {
+		return metadata.Pairs(history.ForwardedCallerKey, name)
+	}
+	return nil
+}
+
+// TLSFlags configure HTTPS on a gateway. It serves plain HTTP unless
+// CertFile is set.
+type TLSFlags struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile verifies the certificates clients may authenticate
+	// with, whose identity is then forwarded to the gRPC server.
+	ClientCAFile string
+}
+
+// Register defines the -http-tls-* flags on fs.
+func (f *TLSFlags) Register(fs *flag.FlagSet) {
+	fs.StringVar(&f.CertFile, "http-tls-cert", "", "certificate to serve HTTPS with, empty for plain HTTP")
+	fs.StringVar(&f.KeyFile, "http-tls-key", "", "private key of -http-tls-cert")
+	fs.StringVar(&f.ClientCAFile, "http-tls-client-ca", "", "CA certificate verifying the client certificates forwarded to the server as the caller")
+}
+
+// Config returns the TLS configuration selected by the flags, nil for plain
+// HTTP.
+func (f *TLSFlags) Config() (*tls.Config, error) {
+	if f.CertFile == "" {
+		return nil, nil
+	}
+	return certs.ServerConfig(f.CertFile, f.KeyFile, f.ClientCAFile)
+}
+
+// SpecHandler serves spec, the OpenAPI document generated from the service's
+// proto by protoc-gen-swagger.
+func SpecHandler(spec []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(spec)
+	})
+}
+
+// WithSpec serves the OpenAPI document spec at SpecPath, and everything else
+// with h.
+func WithSpec(h http.Handler, spec []byte) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(SpecPath, SpecHandler(spec))
+	mux.Handle("/", h)
+	return mux
+}
+
+// LoadSpec returns the OpenAPI document in file, or embedded when file is
+// empty. The file is read once, so that a missing document is reported at
+// startup.
+func LoadSpec(file string, embedded []byte) ([]byte, error) {
+	if file == "" {
+		return embedded, nil
+	}
+	return ioutil.ReadFile(file)
+}
+
+// ListenAndServe serves h on addr, over HTTPS if cfg is not nil, until the
+// process is interrupted, then shuts the server down gracefully.
+func ListenAndServe(addr string, h http.Handler, cfg *tls.Config) {
+	srv := &http.Server{Addr: addr, Handler: h, TLSConfig: cfg}
+
+	go func() {
+		logging.Infof("Starting Gateway at %s", addr)
+		serve := srv.ListenAndServe
+		if cfg != nil {
+			serve = func() error { return srv.ListenAndServeTLS("", "") }
+		}
+		if err := serve(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to serve %v", err)
+		}
+	}()
+
+	//Wait for ctrl+c to exit
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt)
+	<-ch
+
+	logging.Infof("Stopping the gateway")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		logging.Errorf("Failed to shut down cleanly: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package gateway_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"grpc-go-course/blog/blogpb"
+	"grpc-go-course/calculator/calculatorpb"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// root is the module root, relative to this package.
+const root = ".."
+
+// TestSpecsUpToDate regenerates the OpenAPI documents the gateways embed and
+// fails if they differ from the committed ones, so that a proto change
+// without a ./generate.sh run does not ship a stale document.
+func TestSpecsUpToDate(t *testing.T) {
+	for _, tool := range []string{"protoc", "protoc-gen-swagger"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s is not installed", tool)
+		}
+	}
+
+	for _, proto := range []string{
+		"blog/blogpb/blog.proto",
+		"calculator/calculatorpb/calculator.proto",
+	} {
+		t.Run(filepath.Base(proto), func(t *testing.T) {
+			out := t.TempDir()
+			cmd := exec.Command("protoc", "-I", root, "-I", filepath.Join(root, "third_party"), "--swagger_out="+out, filepath.Join(root, proto))
+			if msg, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("protoc failed: %v\n%s", err, msg)
+			}
+
+			spec := proto[:len(proto)-len(".proto")] + ".swagger.json"
+			got, err := ioutil.ReadFile(filepath.Join(out, spec))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := ioutil.ReadFile(filepath.Join(root, spec))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s is out of date, run ./generate.sh", spec)
+			}
+		})
+	}
+}
+
+// TestSpecsMatchRules checks, without protoc, that the embedded OpenAPI
+// documents have exactly one operation for each google.api.http rule of the
+// compiled protos, with the same method and path, so that a proto change that
+// adds, removes or moves an endpoint fails even where TestSpecsUpToDate is
+// skipped.
+func TestSpecsMatchRules(t *testing.T) {
+	for _, tt := range []struct {
+		proto string
+		spec  []byte
+	}{
+		{"blog/blogpb/blog.proto", blogpb.OpenAPI},
+		{"calculator/calculatorpb/calculator.proto", calculatorpb.OpenAPI},
+	} {
+		t.Run(filepath.Base(tt.proto), func(t *testing.T) {
+			fd, err := protoregistry.GlobalFiles.FindFileByPath(tt.proto)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := ruleEndpoints(fd)
+			if len(want) == 0 {
+				t.Fatalf("%s has no google.api.http rule", tt.proto)
+			}
+
+			var doc struct {
+				Paths map[string]map[string]json.RawMessage `json:"paths"`
+			}
+			if err := json.Unmarshal(tt.spec, &doc); err != nil {
+				t.Fatalf("the embedded document is not valid JSON: %v", err)
+			}
+			got := make(map[string]bool)
+			for path, ops := range doc.Paths {
+				for method := range ops {
+					got[strings.ToUpper(method)+" "+path] = true
+				}
+			}
+
+			for e := range want {
+				if !got[e] {
+					t.Errorf("%s is missing from the embedded document, run ./generate.sh", e)
+				}
+			}
+			for e := range got {
+				if !want[e] {
+					t.Errorf("%s is in the embedded document but has no rule, run ./generate.sh", e)
+				}
+			}
+		})
+	}
+}
+
+// pathVariable matches a path template variable, with the segments it
+// matches, if given: {name} or {name=pattern}.
+var pathVariable = regexp.MustCompile(`\{([^}=]+)(=[^}]*)?\}`)
+
+// ruleEndpoints returns the "METHOD path" of every google.api.http rule of
+// the services of fd, including additional bindings, with the path templates
+// written as protoc-gen-swagger writes them.
+func ruleEndpoints(fd protoreflect.FileDescriptor) map[string]bool {
+	endpoints := make(map[string]bool)
+	var add func(rule *annotations.HttpRule)
+	add = func(rule *annotations.HttpRule) {
+		var method, path string
+		switch p := rule.GetPattern().(type) {
+		case *annotations.HttpRule_Get:
+			method, path = "GET", p.Get
+		case *annotations.HttpRule_Put:
+			method, path = "PUT", p.Put
+		case *annotations.HttpRule_Post:
+			method, path = "POST", p.Post
+		case *annotations.HttpRule_Delete:
+			method, path = "DELETE", p.Delete
+		case *annotations.HttpRule_Patch:
+			method, path = "PATCH", p.Patch
+		case *annotations.HttpRule_Custom:
+			method, path = strings.ToUpper(p.Custom.GetKind()), p.Custom.GetPath()
+		}
+		if path != "" {
+			endpoints[method+" "+pathVariable.ReplaceAllString(path, "{$1}")] = true
+		}
+		for _, b := range rule.GetAdditionalBindings() {
+			add(b)
+		}
+	}
+	services := fd.Services()
+	for i := 0; i < services.Len(); i++ {
+		methods := services.Get(i).Methods()
+		for j := 0; j < methods.Len(); j++ {
+			if rule, ok := proto.GetExtension(methods.Get(j).Options(), annotations.E_Http).(*annotations.HttpRule); ok && rule != nil {
+				add(rule)
+			}
+		}
+	}
+	return endpoints
+}
@@ -1,5 +1,5 @@
 #!/bin/bash
 
 protoc greet/greetpb/greet.proto --go_out=plugins=grpc:.
-protoc calculator/calculatorpb/calculator.proto --go_out=plugins=grpc:.
-protoc blog/blogpb/blog.proto --go_out=plugins=grpc:.
+protoc -I . -I third_party calculator/calculatorpb/calculator.proto --go_out=plugins=grpc:. --grpc-gateway_out=. --swagger_out=.
+protoc -I . -I third_party blog/blogpb/blog.proto --go_out=plugins=grpc:. --grpc-gateway_out=. --swagger_out=.
@@ -1,10 +1,13 @@
 module grpc-go-course
 
-go 1.14
+go 1.16
 
 require (
-	github.com/golang/protobuf v1.4.2
+	github.com/golang/protobuf v1.4.3
+	github.com/grpc-ecosystem/grpc-gateway v1.16.0
+	github.com/improbable-eng/grpc-web v0.15.0
 	go.mongodb.org/mongo-driver v1.4.1
-	google.golang.org/grpc v1.31.1
+	google.golang.org/genproto v0.0.0-20210126160654-44e461bb6506
+	google.golang.org/grpc v1.42.0
 	google.golang.org/protobuf v1.25.0
 )
@@ -1,196 +1,101 @@
+// Command greet_client calls the GreetService from the command line, one
+// subcommand per RPC.
+//
+// Usage:
+//
+//	greet_client [global flags] <command> [args]
+//
+// Commands:
+//
+//	greet <first> [last]           Greet
+//	greet-many <first> [last]      GreetManyTimes, printing greetings as they arrive
+//	long-greet [names...]          LongGreet
+//	greet-everyone [names...]      GreetEveryone, printing greetings as they arrive
+//	greet-deadline <first> [last]  GreetWithDeadline, bounded by -timeout
+//
+// long-greet and greet-everyone read one "first [last]" name per line from
+// stdin when no names are given as arguments.
 package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/status"
+	"grpc-go-course/dial"
 	"grpc-go-course/greet/greetpb"
-	"io"
 	"log"
-	"time"
+	"os"
 )
 
+// command is a greet_client subcommand. run receives the arguments following
+// the command name.
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, c greetpb.GreetServiceClient, args []string) error
+}
+
+var commands = []command{
+	{"greet", "greet <first> [last]", runGreet},
+	{"greet-many", "greet-many <first> [last]", runGreetManyTimes},
+	{"long-greet", "long-greet [names...]", runLongGreet},
+	{"greet-everyone", "greet-everyone [names...]", runGreetEveryone},
+	{"greet-deadline", "greet-deadline <first> [last]", runGreetWithDeadline},
+}
+
 func main() {
-	certFile := "ssl/ca.crt"
-	tls := true
-	opts := grpc.WithInsecure()
-	if tls {
-		creds, err := credentials.NewClientTLSFromFile(certFile, "")
-		if err != nil {
-			log.Fatalf("Could not construct credentials %v", err)
-		}
-		opts = grpc.WithTransportCredentials(creds)
+	log.SetFlags(0)
+	log.SetPrefix("greet_client: ")
+
+	var df dial.Flags
+	df.Register(flag.CommandLine, dial.Defaults{
+		Addr:   "localhost:50051",
+		CAFile: "ssl/ca.crt",
+	})
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := findCommand(flag.Arg(0))
+	if !ok {
+		log.Printf("unknown command %q", flag.Arg(0))
+		usage()
+		os.Exit(2)
 	}
 
-	cc, err := grpc.Dial("localhost:50051", opts)
+	cc, err := df.Dial(context.Background())
 	if err != nil {
 		log.Fatalf("Could not connect: %v", err)
 	}
 	defer cc.Close()
 
-	c := greetpb.NewGreetServiceClient(cc)
-	//fmt.Printf("Created client: %f", c)
-
-	doUnary(c)
-	//doServerStreaming(c)
-	//doClientStreaming(c)
-	//doBiDiStreaming(c)
-	//doUnaryWithDeadline(c, 5*time.Second)
-	//doUnaryWithDeadline(c, 1*time.Second)
-}
-
-func doUnary(c greetpb.GreetServiceClient) {
-	req := &greetpb.GreetRequest{
-		Greeting: &greetpb.Greeting{
-			FirstName: "Dom",
-			LastName:  "Lee",
-		},
-	}
-	res, err := c.Greet(context.Background(), req)
-	if err != nil {
-		log.Fatalf("error while calling greet RPC: %v", err)
-	}
-	log.Printf("Response from Greet: %v", res.Result)
-}
-
-func doUnaryWithDeadline(c greetpb.GreetServiceClient, timeout time.Duration) {
-	req := &greetpb.GreetWithDeadlineRequest{
-		Greeting: &greetpb.Greeting{
-			FirstName: "Dom",
-			LastName:  "Lee",
-		},
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := df.CallContext(context.Background())
 	defer cancel()
-
-	res, err := c.GreetWithDeadline(ctx, req)
-	if err != nil {
-		statusErr, ok := status.FromError(err)
-		if ok {
-			if statusErr.Code() == codes.DeadlineExceeded {
-				fmt.Println("Deadline was exceeded")
-			} else {
-				fmt.Printf("Unexpected error %v", statusErr)
-			}
-		} else {
-			log.Fatalf("error while calling GreetWithDeadline RPC: %v", err)
-		}
-		return
+	if err := cmd.run(ctx, greetpb.NewGreetServiceClient(cc), flag.Args()[1:]); err != nil {
+		cancel()
+		cc.Close()
+		log.Fatalf("%s: %v", cmd.name, err)
 	}
-	log.Printf("Response from GreetWithDeadline: %v", res.Result)
 }
 
-func doServerStreaming(c greetpb.GreetServiceClient) {
-	fmt.Println("Starting to do a server streaming RPC")
-	req := &greetpb.GreetManyTimesRequest{
-		Greeting: &greetpb.Greeting{
-			FirstName: "Dummy",
-			LastName:  "Dom",
-		},
-	}
-	resStream, err := c.GreetManyTimes(context.Background(), req)
-	if err != nil {
-		log.Fatalf("Failed to stream %v", err)
-	}
-	for {
-		msg, err := resStream.Recv()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			log.Fatalf("error while reading stream %v", err)
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
 		}
-		log.Printf("Response from GreetManyTimes: %v", msg.GetResult())
-	}
-}
-
-func doClientStreaming(c greetpb.GreetServiceClient) {
-	fmt.Println("Starting to a client streaming RPC")
-	requests := []*greetpb.LongGreetRequest{
-		&greetpb.LongGreetRequest{Greeting: &greetpb.Greeting{
-			FirstName: "Yellow",
-			LastName:  "Goose",
-		}},
-		&greetpb.LongGreetRequest{Greeting: &greetpb.Greeting{
-			FirstName: "Pink",
-			LastName:  "Rhino",
-		}},
-		&greetpb.LongGreetRequest{Greeting: &greetpb.Greeting{
-			FirstName: "Hunter",
-			LastName:  "Smith",
-		}},
-	}
-	stream, err := c.LongGreet(context.Background())
-	if err != nil {
-		log.Fatalf("Error while calling LongGreet: %v", err)
-	}
-
-	for _, req := range requests {
-		fmt.Printf("Sending req %v\n", req)
-		stream.Send(req)
-		time.Sleep(100 * time.Millisecond)
-	}
-	res, err := stream.CloseAndRecv()
-	if err != nil {
-		log.Fatalf("error while receiving response from LongGreet: %v", err)
 	}
-	fmt.Printf("LongGreet Response: %v\n", res)
+	return command{}, false
 }
 
-func doBiDiStreaming(c greetpb.GreetServiceClient) {
-	stream, err := c.GreetEveryone(context.Background())
-	if err != nil {
-		log.Fatalf("Failed to call GreetEveryone %v", err)
+func usage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintf(w, "Usage: greet_client [global flags] <command> [args]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %s\n", cmd.usage)
 	}
-
-	requests := []*greetpb.GreetEveryoneRequest{
-		&greetpb.GreetEveryoneRequest{
-			Greeting: &greetpb.Greeting{
-				FirstName: "Jonny",
-				LastName:  "Cash",
-			}},
-		&greetpb.GreetEveryoneRequest{
-			Greeting: &greetpb.Greeting{
-				FirstName: "Jesus",
-				LastName:  "Wong",
-			}},
-		&greetpb.GreetEveryoneRequest{
-			Greeting: &greetpb.Greeting{
-				FirstName: "Silly",
-				LastName:  "Cow",
-			}},
-	}
-
-	waitChan := make(chan struct{})
-
-	go func() {
-		for _, req := range requests {
-			fmt.Printf("Sending message: %v\n", req)
-			if err := stream.Send(req); err != nil {
-				log.Fatalf("Failed to send message to server %v", err)
-			}
-			time.Sleep(1000 * time.Millisecond)
-		}
-		stream.CloseSend()
-	}()
-
-	go func() {
-		for {
-			res, err := stream.Recv()
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				log.Printf("Failed to receive response %v", err)
-				break
-			}
-			fmt.Printf("Received: %v\n", res.GetResult())
-		}
-		close(waitChan)
-	}()
-
-	<-waitChan
+	fmt.Fprintf(w, "\nlong-greet and greet-everyone read one name per line from stdin when none are given.\n\nGlobal flags:\n")
+	flag.PrintDefaults()
 }
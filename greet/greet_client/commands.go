@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"grpc-go-course/greet/greetpb"
+	"io"
+	"os"
+	"strings"
+)
+
+func runGreet(ctx context.Context, c greetpb.GreetServiceClient, args []string) error {
+	greeting, err := parseGreeting(args)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.Greet(ctx, &greetpb.GreetRequest{Greeting: greeting})
+	if err != nil {
+		return err
+	}
+	fmt.Println(res.GetResult())
+	return nil
+}
+
+func runGreetManyTimes(ctx context.Context, c greetpb.GreetServiceClient, args []string) error {
+	greeting, err := parseGreeting(args)
+	if err != nil {
+		return err
+	}
+
+	stream, err := c.GreetManyTimes(ctx, &greetpb.GreetManyTimesRequest{Greeting: greeting})
+	if err != nil {
+		return err
+	}
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println(msg.GetResult())
+	}
+}
+
+func runLongGreet(ctx context.Context, c greetpb.GreetServiceClient, args []string) error {
+	stream, err := c.LongGreet(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = readGreetings(args, func(g *greetpb.Greeting) error {
+		return stream.Send(&greetpb.LongGreetRequest{Greeting: g})
+	})
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	res, err := stream.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+	fmt.Println(res.GetResult())
+	return nil
+}
+
+func runGreetEveryone(ctx context.Context, c greetpb.GreetServiceClient, args []string) error {
+	stream, err := c.GreetEveryone(ctx)
+	if err != nil {
+		return err
+	}
+
+	sendErr := make(chan error, 1)
+	go func() {
+		err := readGreetings(args, func(g *greetpb.Greeting) error {
+			return stream.Send(&greetpb.GreetEveryoneRequest{Greeting: g})
+		})
+		// A failed Send means the stream is broken; Recv reports why.
+		if err == io.EOF {
+			err = nil
+		}
+		stream.CloseSend()
+		sendErr <- err
+	}()
+
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println(res.GetResult())
+	}
+	return <-sendErr
+}
+
+func runGreetWithDeadline(ctx context.Context, c greetpb.GreetServiceClient, args []string) error {
+	greeting, err := parseGreeting(args)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.GreetWithDeadline(ctx, &greetpb.GreetWithDeadlineRequest{Greeting: greeting})
+	if status.Code(err) == codes.DeadlineExceeded {
+		return errors.New("deadline was exceeded, raise -timeout to wait longer")
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(res.GetResult())
+	return nil
+}
+
+// readGreetings calls fn with a greeting for each name in args, or for each
+// "first [last]" line read from stdin if args is empty. Lines are handed over
+// as soon as they are read, so names can be typed interactively.
+func readGreetings(args []string, fn func(*greetpb.Greeting) error) error {
+	if len(args) > 0 {
+		for _, name := range args {
+			if err := fn(&greetpb.Greeting{FirstName: name}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		greeting, err := parseGreeting(fields)
+		if err != nil {
+			return err
+		}
+		if err := fn(greeting); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func parseGreeting(args []string) (*greetpb.Greeting, error) {
+	switch len(args) {
+	case 1:
+		return &greetpb.Greeting{FirstName: args[0]}, nil
+	case 2:
+		return &greetpb.Greeting{FirstName: args[0], LastName: args[1]}, nil
+	}
+	return nil, fmt.Errorf("expected a first and optional last name, got %d arguments", len(args))
+}
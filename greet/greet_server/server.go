@@ -2,12 +2,17 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/status"
+	"grpc-go-course/admin"
 	"grpc-go-course/greet/greetpb"
+	"grpc-go-course/logging"
+	"grpc-go-course/web"
 	"io"
 	"log"
 	"net"
@@ -18,13 +23,14 @@ import (
 type server struct{}
 
 func (s *server) GreetWithDeadline(ctx context.Context, req *greetpb.GreetWithDeadlineRequest) (*greetpb.GreetWithDeadlineResponse, error) {
-	fmt.Println("Greet function was invoked")
+	logging.Debugf("Greet function was invoked")
 	for i := 0; i < 3; i++ {
-		if ctx.Err() == context.Canceled {
-			fmt.Println("Client canceled the request!")
-			return nil, status.Error(codes.DeadlineExceeded, "The client canceled the request")
+		select {
+		case <-ctx.Done():
+			logging.Debugf("Client gave up on the request: %v", ctx.Err())
+			return nil, status.FromContextError(ctx.Err()).Err()
+		case <-time.After(1 * time.Second):
 		}
-		time.Sleep(1 * time.Second)
 	}
 	firstName := req.GetGreeting().GetFirstName()
 	lastName := req.GetGreeting().GetLastName()
@@ -58,7 +64,7 @@ func (s *server) GreetEveryone(stream greetpb.GreetService_GreetEveryoneServer)
 }
 
 func (s *server) LongGreet(stream greetpb.GreetService_LongGreetServer) error {
-	fmt.Println("LongGreet Func was invoked with a streaming request")
+	logging.Debugf("LongGreet Func was invoked with a streaming request")
 	result := ""
 	for {
 		req, err := stream.Recv()
@@ -75,6 +81,7 @@ func (s *server) LongGreet(stream greetpb.GreetService_LongGreetServer) error {
 }
 
 func (s *server) GreetManyTimes(req *greetpb.GreetManyTimesRequest, stream greetpb.GreetService_GreetManyTimesServer) error {
+	ctx := stream.Context()
 	greeting := fmt.Sprintf("Hello %s %s", req.GetGreeting().GetFirstName(), req.GetGreeting().GetLastName())
 	for i := 0; i < 10; i++ {
 		res := &greetpb.GreetManyTimesResponse{
@@ -83,13 +90,17 @@ func (s *server) GreetManyTimes(req *greetpb.GreetManyTimesRequest, stream greet
 		if err := stream.Send(res); err != nil {
 			return err
 		}
-		time.Sleep(1000 * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-time.After(1000 * time.Millisecond):
+		}
 	}
 	return nil
 }
 
 func (*server) Greet(ctx context.Context, req *greetpb.GreetRequest) (*greetpb.GreetResponse, error) {
-	fmt.Println("Greet function was invoked")
+	logging.Debugf("Greet function was invoked")
 	firstName := req.GetGreeting().GetFirstName()
 	lastName := req.GetGreeting().GetLastName()
 	greeting := fmt.Sprintf("Hello %s %s", firstName, lastName)
@@ -102,18 +113,27 @@ func (*server) Greet(ctx context.Context, req *greetpb.GreetRequest) (*greetpb.G
 //func (*server) GreetManyTimes(ctx context.Context, in *greetpb.GreetManyTimesRequest, opts ...grpc.CallOption) (GreetService_GreetManyTimesClient, error)
 
 func main() {
-	fmt.Println("Hello Server")
+	address := flag.String("addr", "0.0.0.0:50051", "address to listen on")
+	var webFlags web.Flags
+	webFlags.Register(flag.CommandLine)
+	var adminFlags admin.Flags
+	adminFlags.Register(flag.CommandLine)
+	flag.Var(logging.LevelFlag{}, "log-level", "minimum level of the messages logged: debug, info, warn or error")
+	flag.Parse()
+
+	logging.Infof("Hello Server")
 
-	listen, err := net.Listen("tcp", "0.0.0.0:50051")
+	listen, err := net.Listen("tcp", *address)
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
 	opts := []grpc.ServerOption{}
 	tls := true
+	certFile, keyFile := "", ""
 	if tls {
-		certFile := "ssl/server.crt"
-		keyFile := "ssl/server.pem"
+		certFile = "ssl/server.crt"
+		keyFile = "ssl/server.pem"
 		creds, err := credentials.NewServerTLSFromFile(certFile, keyFile)
 		if err != nil {
 			log.Fatalf("Failed loading certs %v", err)
@@ -124,7 +144,24 @@ func main() {
 
 	s := grpc.NewServer(opts...)
 
+	healthSrv := health.NewServer()
 	greetpb.RegisterGreetServiceServer(s, &server{})
+	healthpb.RegisterHealthServer(s, healthSrv)
+	drainer := admin.NewDrainer(healthSrv, "greet.GreetService")
+
+	if _, err := adminFlags.Serve(s, healthSrv, opts...); err != nil {
+		log.Fatalf("Failed to start admin port %v", err)
+	}
+	if _, err := adminFlags.ServeHTTP(admin.HTTPConfig{Flags: flag.CommandLine, Drainer: drainer}); err != nil {
+		log.Fatalf("Failed to start admin HTTP server %v", err)
+	}
+
+	if webFlags.Enabled() {
+		if _, err := webFlags.Serve(s, certFile, keyFile); err != nil {
+			log.Fatalf("Failed to serve gRPC-Web: %v", err)
+		}
+		logging.Infof("Serving gRPC-Web at %s", webFlags.Addr)
+	}
 
 	if err := s.Serve(listen); err != nil {
 		log.Fatalf("Failed to serve %v", err)
@@ -0,0 +1,130 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// mongoTimeout bounds each operation of a MongoStore.
+const mongoTimeout = 10 * time.Second
+
+// MongoStore is a Store keeping records in a MongoDB collection.
+type MongoStore struct {
+	coll *mongo.Collection
+}
+
+// mongoRecord is a Record as stored in MongoDB. MongoDB keeps times to the
+// millisecond, so Time is truncated. The ObjectID is made when the record is
+// added, and starts with the time in seconds, so records are listed in the
+// order they were added, up to records added within the same second by
+// different servers.
+type mongoRecord struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	Time      time.Time          `bson:"time"`
+	Duration  time.Duration      `bson:"duration"`
+	Caller    string             `bson:"caller"`
+	Service   string             `bson:"service"`
+	Method    string             `bson:"method"`
+	Inputs    []string           `bson:"inputs"`
+	Results   []string           `bson:"results"`
+	Truncated bool               `bson:"truncated,omitempty"`
+	Code      string             `bson:"code"`
+	Error     string             `bson:"error,omitempty"`
+}
+
+// NewMongoStore returns a MongoStore keeping records in coll, creating the
+// indexes List needs.
+func NewMongoStore(ctx context.Context, coll *mongo.Collection) (*MongoStore, error) {
+	_, err := coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "service", Value: 1}, {Key: "method", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "time", Value: -1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MongoStore{coll: coll}, nil
+}
+
+func (s *MongoStore) Add(r Record) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+	_, err := s.coll.InsertOne(ctx, mongoRecord{
+		ID:        primitive.NewObjectID(),
+		Time:      r.Time.Truncate(time.Millisecond),
+		Duration:  r.Duration,
+		Caller:    r.Caller,
+		Service:   r.Service,
+		Method:    r.Method,
+		Inputs:    r.Inputs,
+		Results:   r.Results,
+		Truncated: r.Truncated,
+		Code:      r.Code,
+		Error:     r.Error,
+	})
+	return err
+}
+
+func (s *MongoStore) List(f Filter) ([]Record, error) {
+	filter := bson.D{}
+	if f.Service != "" {
+		filter = append(filter, bson.E{Key: "service", Value: f.Service})
+	}
+	if f.Method != "" {
+		filter = append(filter, bson.E{Key: "method", Value: f.Method})
+	}
+	timeRange := bson.D{}
+	if !f.Start.IsZero() {
+		timeRange = append(timeRange, bson.E{Key: "$gte", Value: f.Start})
+	}
+	if !f.End.IsZero() {
+		timeRange = append(timeRange, bson.E{Key: "$lt", Value: f.End})
+	}
+	if len(timeRange) > 0 {
+		filter = append(filter, bson.E{Key: "time", Value: timeRange})
+	}
+	if f.After != "" {
+		id, err := primitive.ObjectIDFromHex(f.After)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q", ErrInvalidCursor, f.After)
+		}
+		filter = append(filter, bson.E{Key: "_id", Value: bson.M{"$lt": id}})
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}})
+	if f.Limit > 0 {
+		opts.SetLimit(int64(f.Limit))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), mongoTimeout)
+	defer cancel()
+	cursor, err := s.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var docs []mongoRecord
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	rs := make([]Record, len(docs))
+	for i, d := range docs {
+		rs[i] = Record{
+			ID:        d.ID.Hex(),
+			Time:      d.Time,
+			Duration:  d.Duration,
+			Caller:    d.Caller,
+			Service:   d.Service,
+			Method:    d.Method,
+			Inputs:    d.Inputs,
+			Results:   d.Results,
+			Truncated: d.Truncated,
+			Code:      d.Code,
+			Error:     d.Error,
+		}
+	}
+	return rs, nil
+}
@@ -0,0 +1,239 @@
+package history
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"grpc-go-course/certs"
+	"grpc-go-course/logging"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxMessages bounds the messages of each direction recorded for a
+	// streaming RPC.
+	maxMessages = 100
+	// maxMessageSize bounds the size of the JSON of a recorded message.
+	maxMessageSize = 16 << 10
+)
+
+// ForwardedCallerKey is the metadata key under which a gateway forwards the
+// identity of the client it authenticated. It is only trusted from the
+// gateways passed to Recorder.TrustGateways.
+const ForwardedCallerKey = "x-forwarded-caller"
+
+// Recorder provides server interceptors adding a Record to a Store for each
+// RPC of some services. Recording is durable: an RPC that succeeded but could
+// not be recorded fails with UNAVAILABLE, so that no call goes unrecorded.
+type Recorder struct {
+	store    Store
+	services map[string]bool
+	// excluded are the full names of the methods not recorded.
+	excluded map[string]bool
+	// gateways are the common names of the clients trusted to forward the
+	// identity of their own clients.
+	gateways map[string]bool
+}
+
+// NewRecorder returns a Recorder recording the RPCs of the named services,
+// such as "calculator.CalculatorService", in store.
+func NewRecorder(store Store, services ...string) *Recorder {
+	r := &Recorder{store: store, services: make(map[string]bool), excluded: make(map[string]bool), gateways: make(map[string]bool)}
+	for _, s := range services {
+		r.services[s] = true
+	}
+	return r
+}
+
+// Exclude makes r skip the methods named, such as
+// "/calculator.CalculatorService/Session", whose messages must not be shown
+// by ListHistory. It must be called before r is used.
+func (r *Recorder) Exclude(fullMethods ...string) {
+	for _, m := range fullMethods {
+		r.excluded[m] = true
+	}
+}
+
+// TrustGateways makes r record the RPCs of the clients authenticated with a
+// certificate whose common name is one of names as made on behalf of the
+// client they forward under ForwardedCallerKey. It must be called before r
+// is used.
+func (r *Recorder) TrustGateways(names ...string) {
+	for _, name := range names {
+		r.gateways[name] = true
+	}
+}
+
+// ServerOptions returns the interceptors recording the RPCs.
+func (r *Recorder) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(r.unaryInterceptor),
+		grpc.ChainStreamInterceptor(r.streamInterceptor),
+	}
+}
+
+func (r *Recorder) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	rec, ok := r.start(ctx, info.FullMethod)
+	if !ok {
+		return handler(ctx, req)
+	}
+	rec.add(&rec.Inputs, req)
+	res, err := handler(ctx, req)
+	if err == nil {
+		rec.add(&rec.Results, res)
+	}
+	if err := r.finish(rec, err); err != nil {
+		return nil, err
+	}
+	return res, err
+}
+
+func (r *Recorder) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	rec, ok := r.start(ss.Context(), info.FullMethod)
+	if !ok {
+		return handler(srv, ss)
+	}
+	err := handler(srv, &recordingStream{ServerStream: ss, rec: rec})
+	if err := r.finish(rec, err); err != nil {
+		return err
+	}
+	return err
+}
+
+// recording is a Record being filled in as an RPC runs.
+type recording struct {
+	// mu guards the record against a handler receiving and sending from
+	// different goroutines.
+	mu sync.Mutex
+	Record
+}
+
+func (r *Recorder) start(ctx context.Context, fullMethod string) (*recording, bool) {
+	// fullMethod is "/package.Service/Method".
+	parts := strings.SplitN(strings.TrimPrefix(fullMethod, "/"), "/", 2)
+	if len(parts) != 2 || !r.services[parts[0]] || r.excluded[fullMethod] {
+		return nil, false
+	}
+	return &recording{Record: Record{
+		Time:    time.Now(),
+		Caller:  r.caller(ctx),
+		Service: parts[0],
+		Method:  parts[1],
+	}}, true
+}
+
+func (rec *recording) add(msgs *[]string, m interface{}) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(*msgs) >= maxMessages {
+		rec.Truncated = true
+		return
+	}
+	pm, ok := m.(proto.Message)
+	if !ok {
+		return
+	}
+	data, err := protojson.Marshal(pm)
+	if err != nil {
+		logging.Warnf("Failed to record %T: %v", m, err)
+		return
+	}
+	if len(data) > maxMessageSize {
+		data = data[:maxMessageSize]
+		rec.Truncated = true
+	}
+	*msgs = append(*msgs, string(data))
+}
+
+// finish stores rec, the record of an RPC that ended with err. It returns
+// the error to fail a successful RPC with if rec could not be stored.
+func (r *Recorder) finish(rec *recording, err error) error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.Duration = time.Since(rec.Time)
+	st := status.Convert(err)
+	rec.Code = st.Code().String()
+	rec.Error = st.Message()
+	if addErr := r.store.Add(rec.Record); addErr != nil {
+		logging.Errorf("Failed to record %s/%s: %v", rec.Service, rec.Method, addErr)
+		if err == nil {
+			return status.Error(codes.Unavailable, "Failed to record the call in the history")
+		}
+	}
+	return nil
+}
+
+// recordingStream records the messages of a streaming RPC.
+type recordingStream struct {
+	grpc.ServerStream
+	rec *recording
+}
+
+func (s *recordingStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.rec.add(&s.rec.Inputs, m)
+	}
+	return err
+}
+
+func (s *recordingStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.rec.add(&s.rec.Results, m)
+	}
+	return err
+}
+
+// caller identifies the client of an RPC like CallerFromContext, except that
+// the RPCs of trusted gateways are attributed to the client they forward, as
+// "client via gateway". A gateway forwarding no client is recorded as
+// "unauthenticated via gateway".
+func (r *Recorder) caller(ctx context.Context) string {
+	name, ok := AuthenticatedCaller(ctx)
+	if !ok || !r.gateways[name] {
+		return CallerFromContext(ctx)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if forwarded := md.Get(ForwardedCallerKey); len(forwarded) == 1 && forwarded[0] != "" {
+		return forwarded[0] + " via " + name
+	}
+	return "unauthenticated via " + name
+}
+
+// CallerFromContext identifies the client of an RPC by the common name of the
+// certificate it authenticated with. Clients without one, including every
+// plaintext client, are unidentified: they are reported as
+// "unauthenticated (address)", their address being only a hint.
+func CallerFromContext(ctx context.Context) string {
+	if name, ok := AuthenticatedCaller(ctx); ok {
+		return name
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "unauthenticated"
+	}
+	return "unauthenticated (" + p.Addr.String() + ")"
+}
+
+// AuthenticatedCaller returns the common name of the certificate the client of
+// an RPC authenticated with, or false if it did not present one.
+func AuthenticatedCaller(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return "", false
+	}
+	return certs.CommonName(info.State)
+}
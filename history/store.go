@@ -0,0 +1,142 @@
+// Package history records the RPCs handled by a server, who called them with
+// what and what they returned, and keeps the records in a Store to be listed
+// later.
+package history
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrInvalidCursor is returned by Store.List when Filter.After is not an ID
+// the store could have assigned.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Record is a recorded RPC.
+type Record struct {
+	// ID identifies the record. It is assigned by the Store, and orders the
+	// records by when they were added.
+	ID string
+	// Time is when the RPC started.
+	Time     time.Time
+	Duration time.Duration
+	// Caller identifies the client, see CallerFromContext and
+	// Recorder.TrustGateways.
+	Caller  string
+	Service string
+	Method  string
+	// Inputs and Results are the messages received and sent, as JSON.
+	Inputs  []string
+	Results []string
+	// Truncated is set if messages were left out of Inputs or Results, or
+	// shortened, for being too many or too large.
+	Truncated bool
+	// Code is the name of the status code the RPC returned, "OK" on success,
+	// and Error its message.
+	Code  string
+	Error string
+}
+
+// Filter selects the records listed by a Store.
+type Filter struct {
+	// Service and Method, if not empty, select the records of a service or
+	// method.
+	Service string
+	Method  string
+	// Start and End, if not zero, select the records of RPCs started at or
+	// after Start and before End.
+	Start, End time.Time
+	// After, if not empty, is the ID of the last record of the previous
+	// page: only the records added before it are listed.
+	After string
+	// Limit bounds the number of records returned.
+	Limit int
+}
+
+func (f Filter) match(r Record) bool {
+	return (f.Service == "" || r.Service == f.Service) &&
+		(f.Method == "" || r.Method == f.Method) &&
+		(f.Start.IsZero() || !r.Time.Before(f.Start)) &&
+		(f.End.IsZero() || r.Time.Before(f.End))
+}
+
+// Store keeps records. Implementations must be safe for concurrent use.
+type Store interface {
+	// Add stores r, assigning its ID.
+	Add(r Record) error
+	// List returns the records matching f, the last added first. Records
+	// are added when their RPC finishes, so that a page never misses a
+	// long RPC that started before the records of the previous pages.
+	List(f Filter) ([]Record, error)
+}
+
+// MemoryStore is a Store keeping the latest records in memory, lost when the
+// server restarts.
+type MemoryStore struct {
+	mu  sync.Mutex
+	seq uint64
+	max int
+	// records is a ring buffer of the last max records in the order they
+	// were added, the oldest at start once it is full. The record at i
+	// (from start) has the ID seq - len(records) + 1 + i.
+	records []Record
+	start   int
+}
+
+// NewMemoryStore returns an empty MemoryStore keeping at most max records,
+// dropping the oldest ones beyond that.
+func NewMemoryStore(max int) *MemoryStore {
+	return &MemoryStore{max: max}
+}
+
+func (s *MemoryStore) Add(r Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	// Fixed width hexadecimal IDs sort in the order they were assigned.
+	r.ID = fmt.Sprintf("%016x", s.seq)
+	if len(s.records) < s.max {
+		s.records = append(s.records, r)
+		return nil
+	}
+	s.records[s.start] = r
+	s.start = (s.start + 1) % len(s.records)
+	return nil
+}
+
+func (s *MemoryStore) List(f Filter) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// The records before After are found from its ID, without a search.
+	last := len(s.records) - 1
+	if f.After != "" {
+		after, err := strconv.ParseUint(f.After, 16, 64)
+		if err != nil || len(f.After) != 16 {
+			return nil, fmt.Errorf("%w %q", ErrInvalidCursor, f.After)
+		}
+		oldest := s.seq - uint64(len(s.records)) + 1
+		switch {
+		case after <= oldest:
+			last = -1
+		case after <= s.seq:
+			last = int(after - oldest - 1)
+		}
+	}
+	var rs []Record
+	for i := last; i >= 0 && (f.Limit <= 0 || len(rs) < f.Limit); i-- {
+		r := s.records[(s.start+i)%len(s.records)]
+		if f.match(r) {
+			rs = append(rs, r)
+		}
+	}
+	// Records are never modified once added, but the slices of the caller's
+	// copies must not share the store's.
+	for i := range rs {
+		rs[i].Inputs = append([]string(nil), rs[i].Inputs...)
+		rs[i].Results = append([]string(nil), rs[i].Results...)
+	}
+	return rs, nil
+}
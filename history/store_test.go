@@ -0,0 +1,132 @@
+package history_test
+
+import (
+	"errors"
+	"grpc-go-course/history"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// listAllRecords lists the records matching f a page of size records at a
+// time.
+func listAllRecords(t *testing.T, s history.Store, f history.Filter, size int) []history.Record {
+	t.Helper()
+	var all []history.Record
+	f.Limit = size
+	for {
+		rs, err := s.List(f)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		all = append(all, rs...)
+		if len(rs) < size {
+			return all
+		}
+		f.After = rs[len(rs)-1].ID
+	}
+}
+
+// listAll is listAllRecords returning the methods of the records.
+func listAll(t *testing.T, s history.Store, f history.Filter, size int) []string {
+	t.Helper()
+	var methods []string
+	for _, r := range listAllRecords(t, s, f, size) {
+		methods = append(methods, r.Method)
+	}
+	return methods
+}
+
+func TestMemoryStoreList(t *testing.T) {
+	t0 := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	s := history.NewMemoryStore(100)
+	add := func(method string, start time.Duration) {
+		if err := s.Add(history.Record{Service: "svc", Method: method, Time: t0.Add(start)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("A", 1*time.Second)
+	add("B", 2*time.Second)
+	rs, err := s.List(history.Filter{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := rs[0].ID
+
+	// A long call that started before A finishes after B was listed: it is
+	// listed before B, with the records added since, not in the middle of
+	// the records already seen.
+	add("Long", 0)
+	add("C", 3*time.Second)
+	var got []string
+	for _, r := range listAllRecords(t, s, history.Filter{}, 1) {
+		if r.ID == seen {
+			break
+		}
+		got = append(got, r.Method)
+	}
+	if want := []string{"C", "Long"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("the records added since B are %v, want %v", got, want)
+	}
+
+	add("D", 4*time.Second)
+	for _, size := range []int{1, 2, 3, 10} {
+		got := listAll(t, s, history.Filter{}, size)
+		if want := []string{"D", "C", "Long", "B", "A"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("pages of %d list %v, want %v", size, got, want)
+		}
+	}
+
+	got = listAll(t, s, history.Filter{Start: t0.Add(time.Second), End: t0.Add(4 * time.Second)}, 2)
+	if want := []string{"C", "B", "A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("the records of RPCs started in [1s, 4s) are %v, want %v", got, want)
+	}
+	got = listAll(t, s, history.Filter{Method: "Long"}, 2)
+	if want := []string{"Long"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("the records of Long are %v, want %v", got, want)
+	}
+}
+
+func TestMemoryStoreEviction(t *testing.T) {
+	s := history.NewMemoryStore(3)
+	var ids []string
+	for i := 0; i < 8; i++ {
+		if err := s.Add(history.Record{Method: string(rune('A' + i))}); err != nil {
+			t.Fatal(err)
+		}
+		rs, err := s.List(history.Filter{Limit: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, rs[0].ID)
+
+		// Only the last three records are kept, across the wrap around of
+		// the ring.
+		got := listAll(t, s, history.Filter{}, 2)
+		var want []string
+		for j := i; j >= 0 && j > i-3; j-- {
+			want = append(want, string(rune('A'+j)))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("after adding %d records, the store lists %v, want %v", i+1, got, want)
+		}
+	}
+
+	// The records kept, F, G and H, that were added before an ID, evicted or
+	// not.
+	for i, want := range [][]string{nil, nil, nil, nil, nil, nil, {"F"}, {"G", "F"}} {
+		if got := listAll(t, s, history.Filter{After: ids[i]}, 10); !reflect.DeepEqual(got, want) {
+			t.Errorf("the records before %s are %v, want %v", ids[i], got, want)
+		}
+	}
+}
+
+func TestMemoryStoreInvalidCursor(t *testing.T) {
+	s := history.NewMemoryStore(3)
+	s.Add(history.Record{Method: "A"})
+	for _, after := range []string{"x", "1", "000000000000000g", "-00000000000001", "00000000000000001"} {
+		if _, err := s.List(history.Filter{After: after}); !errors.Is(err, history.ErrInvalidCursor) {
+			t.Errorf("List after %q = %v, want %v", after, err, history.ErrInvalidCursor)
+		}
+	}
+}
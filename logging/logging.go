@@ -0,0 +1,92 @@
+// Package logging adds levels on top of the standard logger, so that the
+// verbosity of a running server can be changed from its admin port.
+package logging
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// Level is the severity of a log message.
+type Level int32
+
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < Debug || l > Error {
+		return fmt.Sprintf("Level(%d)", int32(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parses a level name such as "debug" or "WARN".
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return Level(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q, want one of %s", s, strings.Join(levelNames, ", "))
+}
+
+var level = int32(Info)
+
+// SetLevel sets the minimum level of the messages that are logged.
+func SetLevel(l Level) {
+	atomic.StoreInt32(&level, int32(l))
+}
+
+// GetLevel returns the minimum level of the messages that are logged.
+func GetLevel() Level {
+	return Level(atomic.LoadInt32(&level))
+}
+
+// Enabled reports whether messages at level l are logged.
+func Enabled(l Level) bool {
+	return l >= GetLevel()
+}
+
+// LevelFlag is a flag.Value setting the global level, for use with
+// flag.Var(logging.LevelFlag{}, "log-level", ...).
+type LevelFlag struct{}
+
+func (LevelFlag) String() string {
+	return GetLevel().String()
+}
+
+func (LevelFlag) Set(s string) error {
+	l, err := ParseLevel(s)
+	if err != nil {
+		return err
+	}
+	SetLevel(l)
+	return nil
+}
+
+func logf(l Level, format string, v ...interface{}) {
+	if !Enabled(l) {
+		return
+	}
+	log.Output(3, strings.ToUpper(l.String())+" "+fmt.Sprintf(format, v...))
+}
+
+// Debugf logs at Debug level.
+func Debugf(format string, v ...interface{}) { logf(Debug, format, v...) }
+
+// Infof logs at Info level.
+func Infof(format string, v ...interface{}) { logf(Info, format, v...) }
+
+// Warnf logs at Warn level.
+func Warnf(format string, v ...interface{}) { logf(Warn, format, v...) }
+
+// Errorf logs at Error level.
+func Errorf(format string, v ...interface{}) { logf(Error, format, v...) }
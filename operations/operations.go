@@ -0,0 +1,311 @@
+// Package operations runs expensive work in the background as long-running
+// operations, and serves them through the google.longrunning Operations API,
+// so that clients can poll, wait for or cancel the work instead of keeping a
+// call open.
+package operations
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/genproto/googleapis/longrunning"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"grpc-go-course/logging"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// Collection is the parent resource of every operation, and the prefix
+	// of their names.
+	Collection = "operations"
+
+	defaultPageSize = 50
+	maxPageSize     = 1000
+)
+
+// Progress reports the progress of an operation by replacing its metadata.
+type Progress func(metadata proto.Message)
+
+// Func is the work of an operation. It returns the response of the operation,
+// or an error, preferably a gRPC status. It must return promptly once ctx is
+// cancelled.
+type Func func(ctx context.Context, progress Progress) (proto.Message, error)
+
+// Server runs operations and implements the google.longrunning Operations
+// service.
+type Server struct {
+	longrunning.UnimplementedOperationsServer
+
+	store      Store
+	maxRunning int
+	timeout    time.Duration
+	retention  time.Duration
+
+	mu      sync.Mutex
+	running map[string]*task
+}
+
+type task struct {
+	op     *longrunning.Operation
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewServer returns a Server keeping operations in store. At most maxRunning
+// operations run at once, each for at most timeout if it is positive, and
+// finished operations are deleted after retention.
+func NewServer(store Store, maxRunning int, timeout, retention time.Duration) *Server {
+	return &Server{
+		store:      store,
+		maxRunning: maxRunning,
+		timeout:    timeout,
+		retention:  retention,
+		running:    map[string]*task{},
+	}
+}
+
+// Start starts an operation running fn in the background, and returns it.
+func (s *Server) Start(metadata proto.Message, fn Func) (*longrunning.Operation, error) {
+	// Operations are deleted once their retention has passed, but a store
+	// that outlives the server may still hold some finished before it
+	// started.
+	if err := s.store.Expire(time.Now().Add(-s.retention)); err != nil {
+		logging.Errorf("Failed to expire operations %v", err)
+	}
+
+	md, err := anypb.New(metadata)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Cannot marshal operation metadata %v", err)
+	}
+	name, err := newName()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Cannot name operation %v", err)
+	}
+	op := &longrunning.Operation{Name: name, Metadata: md}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.running) >= s.maxRunning {
+		return nil, status.Errorf(codes.ResourceExhausted, "Too many running operations, at most %d", s.maxRunning)
+	}
+	if _, err := s.store.Create(op); err != nil {
+		return nil, status.Errorf(codes.Internal, "Cannot store operation %v", err)
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if s.timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), s.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	t := &task{op: op, cancel: cancel, done: make(chan struct{})}
+	s.running[name] = t
+	go s.run(ctx, t, fn)
+	return proto.Clone(op).(*longrunning.Operation), nil
+}
+
+func newName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return Collection + "/" + hex.EncodeToString(b), nil
+}
+
+func (s *Server) run(ctx context.Context, t *task, fn Func) {
+	progress := func(metadata proto.Message) {
+		md, err := anypb.New(metadata)
+		if err != nil {
+			logging.Errorf("Cannot marshal metadata of %s %v", t.op.GetName(), err)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		t.op.Metadata = md
+		if err := s.store.Update(t.op, time.Time{}); err != nil && err != ErrNotFound {
+			logging.Errorf("Failed to update %s %v", t.op.GetName(), err)
+		}
+	}
+	res, err := fn(ctx, progress)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t.op.Done = true
+	switch {
+	case err != nil && ctx.Err() == context.DeadlineExceeded:
+		t.op.Result = &longrunning.Operation_Error{Error: status.Newf(codes.DeadlineExceeded, "Operation took longer than %v", s.timeout).Proto()}
+	case err != nil && ctx.Err() != nil:
+		t.op.Result = &longrunning.Operation_Error{Error: status.New(codes.Canceled, "Operation cancelled").Proto()}
+	case err != nil:
+		t.op.Result = &longrunning.Operation_Error{Error: status.Convert(err).Proto()}
+	default:
+		resAny, err := anypb.New(res)
+		if err != nil {
+			t.op.Result = &longrunning.Operation_Error{Error: status.Newf(codes.Internal, "Cannot marshal response %v", err).Proto()}
+		} else {
+			t.op.Result = &longrunning.Operation_Response{Response: resAny}
+		}
+	}
+	// A deleted operation is dropped
+	if err := s.store.Update(t.op, time.Now()); err != nil && err != ErrNotFound {
+		logging.Errorf("Failed to update %s %v", t.op.GetName(), err)
+	}
+	t.cancel()
+	delete(s.running, t.op.GetName())
+	close(t.done)
+
+	name := t.op.GetName()
+	time.AfterFunc(s.retention, func() {
+		if err := s.store.Delete(name); err != nil && err != ErrNotFound {
+			logging.Errorf("Failed to expire %s %v", name, err)
+		}
+	})
+}
+
+func (s *Server) GetOperation(ctx context.Context, req *longrunning.GetOperationRequest) (*longrunning.Operation, error) {
+	r, err := s.get(req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	return r.Operation, nil
+}
+
+func (s *Server) get(name string) (Record, error) {
+	r, err := s.store.Get(name)
+	if err == ErrNotFound {
+		return Record{}, status.Errorf(codes.NotFound, "Cannot find operation %q", name)
+	}
+	if err != nil {
+		return Record{}, status.Errorf(codes.Internal, "Cannot read operation %v", err)
+	}
+	return r, nil
+}
+
+// doneFilter matches the only filters ListOperations supports.
+var doneFilter = regexp.MustCompile(`^\s*done\s*=\s*(true|false)\s*$`)
+
+func (s *Server) ListOperations(ctx context.Context, req *longrunning.ListOperationsRequest) (*longrunning.ListOperationsResponse, error) {
+	if name := req.GetName(); name != "" && name != Collection {
+		return nil, status.Errorf(codes.InvalidArgument, "Operations are listed under %q, not %q", Collection, name)
+	}
+
+	var f Filter
+	if filter := req.GetFilter(); filter != "" {
+		m := doneFilter.FindStringSubmatch(filter)
+		if m == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Unsupported filter %q, want done=true or done=false", filter)
+		}
+		done := m[1] == "true"
+		f.Done = &done
+	}
+
+	size := int(req.GetPageSize())
+	switch {
+	case size < 0:
+		return nil, status.Errorf(codes.InvalidArgument, "Negative page size %d", size)
+	case size == 0:
+		size = defaultPageSize
+	case size > maxPageSize:
+		size = maxPageSize
+	}
+	f.Limit = size + 1
+
+	if token := req.GetPageToken(); token != "" {
+		seq, err := decodePageToken(token)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid page token %q", token)
+		}
+		f.AfterSeq = seq
+	}
+
+	rs, err := s.store.List(f)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Cannot list operations %v", err)
+	}
+	res := &longrunning.ListOperationsResponse{}
+	if len(rs) > size {
+		rs = rs[:size]
+		res.NextPageToken = encodePageToken(rs[size-1].Seq)
+	}
+	for _, r := range rs {
+		res.Operations = append(res.Operations, r.Operation)
+	}
+	return res, nil
+}
+
+func encodePageToken(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(seq, 10)))
+}
+
+func decodePageToken(token string) (int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(b), 10, 64)
+}
+
+// DeleteOperation forgets an operation. Like the google.longrunning API
+// specifies, it doesn't cancel it.
+func (s *Server) DeleteOperation(ctx context.Context, req *longrunning.DeleteOperationRequest) (*empty.Empty, error) {
+	err := s.store.Delete(req.GetName())
+	if err == ErrNotFound {
+		return nil, status.Errorf(codes.NotFound, "Cannot find operation %q", req.GetName())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Cannot delete operation %v", err)
+	}
+	return &empty.Empty{}, nil
+}
+
+func (s *Server) CancelOperation(ctx context.Context, req *longrunning.CancelOperationRequest) (*empty.Empty, error) {
+	if _, err := s.get(req.GetName()); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	if t, ok := s.running[req.GetName()]; ok {
+		t.cancel()
+	}
+	s.mu.Unlock()
+	return &empty.Empty{}, nil
+}
+
+// WaitOperation waits for the operation to finish, for at most the timeout of
+// the request, if any, or until the call's deadline.
+func (s *Server) WaitOperation(ctx context.Context, req *longrunning.WaitOperationRequest) (*longrunning.Operation, error) {
+	if _, err := s.get(req.GetName()); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	t, ok := s.running[req.GetName()]
+	s.mu.Unlock()
+	if ok {
+		var timeout <-chan time.Time
+		if req.GetTimeout() != nil {
+			d := req.GetTimeout().AsDuration()
+			if d < 0 {
+				return nil, status.Errorf(codes.InvalidArgument, "Negative timeout %v", d)
+			}
+			timer := time.NewTimer(d)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case <-t.done:
+		case <-timeout:
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+	return s.GetOperation(ctx, &longrunning.GetOperationRequest{Name: req.GetName()})
+}
@@ -0,0 +1,99 @@
+package operations_test
+
+import (
+	"context"
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/genproto/googleapis/longrunning"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"grpc-go-course/operations"
+	"testing"
+	"time"
+)
+
+// wait waits for the operation named name to finish.
+func wait(t *testing.T, s *operations.Server, name string) *longrunning.Operation {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	op, err := s.WaitOperation(ctx, &longrunning.WaitOperationRequest{Name: name})
+	if err != nil {
+		t.Fatalf("WaitOperation: %v", err)
+	}
+	if !op.GetDone() {
+		t.Fatalf("%s is not done", name)
+	}
+	return op
+}
+
+func TestTimeout(t *testing.T) {
+	s := operations.NewServer(operations.NewMemoryStore(), 1, 20*time.Millisecond, time.Hour)
+	op, err := s.Start(&empty.Empty{}, func(ctx context.Context, _ operations.Progress) (proto.Message, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	op = wait(t, s, op.GetName())
+	if code := codes.Code(op.GetError().GetCode()); code != codes.DeadlineExceeded {
+		t.Errorf("the operation failed with %v, want %v", status.FromProto(op.GetError()).Err(), codes.DeadlineExceeded)
+	}
+
+	// The timed out operation no longer counts against the running ones.
+	op, err = s.Start(&empty.Empty{}, func(context.Context, operations.Progress) (proto.Message, error) {
+		return &empty.Empty{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op = wait(t, s, op.GetName()); op.GetResponse() == nil {
+		t.Errorf("the operation failed with %v", status.FromProto(op.GetError()).Err())
+	}
+}
+
+func TestCancel(t *testing.T) {
+	s := operations.NewServer(operations.NewMemoryStore(), 1, time.Hour, time.Hour)
+	op, err := s.Start(&empty.Empty{}, func(ctx context.Context, _ operations.Progress) (proto.Message, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CancelOperation(context.Background(), &longrunning.CancelOperationRequest{Name: op.GetName()}); err != nil {
+		t.Fatal(err)
+	}
+	op = wait(t, s, op.GetName())
+	if code := codes.Code(op.GetError().GetCode()); code != codes.Canceled {
+		t.Errorf("the operation failed with %v, want %v", status.FromProto(op.GetError()).Err(), codes.Canceled)
+	}
+}
+
+// TestRetention checks that finished operations are deleted once their
+// retention has passed, without another operation being started.
+func TestRetention(t *testing.T) {
+	s := operations.NewServer(operations.NewMemoryStore(), 1, 0, 50*time.Millisecond)
+	op, err := s.Start(&empty.Empty{}, func(context.Context, operations.Progress) (proto.Message, error) {
+		return &empty.Empty{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait(t, s, op.GetName())
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := s.GetOperation(context.Background(), &longrunning.GetOperationRequest{Name: op.GetName()})
+		if status.Code(err) == codes.NotFound {
+			return
+		}
+		if err != nil {
+			t.Fatalf("GetOperation: %v", err)
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s was not deleted after its retention", op.GetName())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
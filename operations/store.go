@@ -0,0 +1,139 @@
+package operations
+
+import (
+	"errors"
+	"google.golang.org/genproto/googleapis/longrunning"
+	"google.golang.org/protobuf/proto"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by a Store for operations it doesn't hold.
+var ErrNotFound = errors.New("operation not found")
+
+// Record is an operation as kept by a Store.
+type Record struct {
+	// Seq orders operations by creation. It is assigned by the Store.
+	Seq       int64
+	Operation *longrunning.Operation
+	// Finished is when the operation completed, zero while it runs.
+	Finished time.Time
+}
+
+// Filter selects the operations listed by a Store.
+type Filter struct {
+	// Done, if not nil, selects only finished or only running operations.
+	Done *bool
+	// AfterSeq skips the operations created before and including AfterSeq.
+	AfterSeq int64
+	// Limit bounds the number of operations returned.
+	Limit int
+}
+
+// Store keeps operations. Implementations must be safe for concurrent use,
+// and store and return copies of the operations.
+type Store interface {
+	Create(op *longrunning.Operation) (Record, error)
+	Get(name string) (Record, error)
+	Update(op *longrunning.Operation, finished time.Time) error
+	Delete(name string) error
+	// List returns the operations matching f, oldest first.
+	List(f Filter) ([]Record, error)
+	// Expire deletes the operations finished before t.
+	Expire(t time.Time) error
+}
+
+// MemoryStore is a Store keeping operations in memory, lost when the server
+// restarts.
+type MemoryStore struct {
+	mu      sync.Mutex
+	seq     int64
+	records map[string]Record
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: map[string]Record{}}
+}
+
+func (s *MemoryStore) Create(op *longrunning.Operation) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	r := Record{Seq: s.seq, Operation: proto.Clone(op).(*longrunning.Operation)}
+	s.records[op.GetName()] = r
+	return copyRecord(r), nil
+}
+
+func (s *MemoryStore) Get(name string) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.records[name]
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+	return copyRecord(r), nil
+}
+
+func (s *MemoryStore) Update(op *longrunning.Operation, finished time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.records[op.GetName()]
+	if !ok {
+		return ErrNotFound
+	}
+	r.Operation = proto.Clone(op).(*longrunning.Operation)
+	r.Finished = finished
+	s.records[op.GetName()] = r
+	return nil
+}
+
+func (s *MemoryStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.records[name]; !ok {
+		return ErrNotFound
+	}
+	delete(s.records, name)
+	return nil
+}
+
+func (s *MemoryStore) List(f Filter) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var rs []Record
+	for _, r := range s.records {
+		if r.Seq <= f.AfterSeq {
+			continue
+		}
+		if f.Done != nil && r.Operation.GetDone() != *f.Done {
+			continue
+		}
+		rs = append(rs, r)
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i].Seq < rs[j].Seq })
+	if f.Limit > 0 && len(rs) > f.Limit {
+		rs = rs[:f.Limit]
+	}
+	for i := range rs {
+		rs[i] = copyRecord(rs[i])
+	}
+	return rs, nil
+}
+
+func (s *MemoryStore) Expire(t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, r := range s.records {
+		if !r.Finished.IsZero() && r.Finished.Before(t) {
+			delete(s.records, name)
+		}
+	}
+	return nil
+}
+
+func copyRecord(r Record) Record {
+	r.Operation = proto.Clone(r.Operation).(*longrunning.Operation)
+	return r
+}
@@ -0,0 +1,41 @@
+// Package version describes the build of the running binary. Version, Commit
+// and BuildDate are meant to be set at build time:
+//
+//	go build -ldflags "-X grpc-go-course/version.Version=1.2.0 -X grpc-go-course/version.Commit=$(git rev-parse HEAD)"
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// Info is the build information reported by the admin port.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+	Path      string `json:"path,omitempty"`
+	Module    string `json:"module,omitempty"`
+}
+
+// Get returns the build information of the running binary.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.Path = bi.Path
+		info.Module = bi.Main.Path + "@" + bi.Main.Version
+	}
+	return info
+}
@@ -0,0 +1,81 @@
+// Package web serves a gRPC server to browsers over gRPC-Web, alongside its
+// regular gRPC listener.
+package web
+
+import (
+	"flag"
+	"github.com/improbable-eng/grpc-web/go/grpcweb"
+	"google.golang.org/grpc"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Flags configure the gRPC-Web listener. It is off unless Addr is set.
+type Flags struct {
+	Addr string
+	// AllowedOrigins is a comma separated list of the origins allowed to
+	// make cross-origin calls, or "*" to allow any origin.
+	AllowedOrigins string
+}
+
+// Register defines the -grpc-web-* flags on fs.
+func (f *Flags) Register(fs *flag.FlagSet) {
+	fs.StringVar(&f.Addr, "grpc-web-addr", "", "address to serve gRPC-Web on, empty to disable")
+	fs.StringVar(&f.AllowedOrigins, "grpc-web-allowed-origins", "", `comma separated origins allowed to call over gRPC-Web, or "*" for any`)
+}
+
+// Enabled reports whether the gRPC-Web listener is turned on.
+func (f *Flags) Enabled() bool {
+	return f.Addr != ""
+}
+
+// Serve serves s over gRPC-Web on f.Addr in the background, with TLS if
+// certFile and keyFile are set. Server streaming RPCs are sent as chunked
+// HTTP/1.1 responses, flushed after every message, unless the browser
+// negotiates HTTP/2 over TLS. The returned server should be shut down along
+// with s.
+func (f *Flags) Serve(s *grpc.Server, certFile, keyFile string) (*http.Server, error) {
+	wrapped := grpcweb.WrapServer(s,
+		grpcweb.WithOriginFunc(f.allowOrigin),
+		grpcweb.WithCorsForRegisteredEndpointsOnly(true),
+		grpcweb.WithAllowedRequestHeaders([]string{"authorization"}),
+	)
+	srv := &http.Server{
+		Addr: f.Addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if wrapped.IsGrpcWebRequest(r) || wrapped.IsAcceptableGrpcCorsRequest(r) {
+				wrapped.ServeHTTP(w, r)
+				return
+			}
+			http.NotFound(w, r)
+		}),
+	}
+
+	listen, err := net.Listen("tcp", f.Addr)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		var err error
+		if certFile != "" {
+			err = srv.ServeTLS(listen, certFile, keyFile)
+		} else {
+			err = srv.Serve(listen)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to serve gRPC-Web %v", err)
+		}
+	}()
+	return srv, nil
+}
+
+func (f *Flags) allowOrigin(origin string) bool {
+	for _, o := range strings.Split(f.AllowedOrigins, ",") {
+		if o = strings.TrimSpace(o); o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}